@@ -1,7 +1,7 @@
 package admin
 
 import (
-	"errors"
+	"fmt"
 	"github.com/Shopify/sarama"
 	"github.com/thimico/kafka-cli/kafka"
 	"github.com/thimico/kafka-cli/log"
@@ -41,148 +41,183 @@ var adminExample = `
 `
 
 type adminOptions struct {
-	bootstrapServers string
-	groups string
-	topics string
+	client *kafka.ClientOptions
+
+	groups     string
+	topics     string
 	partitions string
-	brokers string
-	offset int64
+	brokers    string
+	offset     int64
 
-	deleteRecords bool
-	listConsumerGroups bool
-	describeGroups bool
-	deleteGroups bool
+	deleteRecords       bool
+	listConsumerGroups  bool
+	describeGroups      bool
+	deleteGroups        bool
 	listConsumerOffsets bool
-	describeCluster bool
-	describeLogDirs bool
+	describeCluster     bool
+	describeLogDirs     bool
 }
 
-func newAdminOptions() *adminOptions {
-	return &adminOptions{}
+func newAdminOptions(clientOptions *kafka.ClientOptions) *adminOptions {
+	return &adminOptions{client: clientOptions}
 }
 
 func (o *adminOptions) validate() error {
 	if o.deleteRecords {
 		if o.topics == "" || o.partitions == "" || o.offset == 0 {
-			return errors.New("when delete records, topics or partitions and offset should not be empty")
+			return utils.UsageError("when delete records, topics or partitions and offset should not be empty")
 		}
 	}
 	if o.describeGroups {
 		if o.groups == "" {
-			return errors.New("when describe groups, groups flags should not be empty")
+			return utils.UsageError("when describe groups, groups flags should not be empty")
 		}
 	}
 	if o.deleteGroups {
 		if o.groups == "" {
-			return errors.New("when delete groups, groups flag should not be empty")
+			return utils.UsageError("when delete groups, groups flag should not be empty")
 		}
 	}
 	if o.listConsumerOffsets {
 		if o.topics == "" || o.partitions == "" {
-			return errors.New("when list consumer offsets, groups, topics and partitions should not be empty")
+			return utils.UsageError("when list consumer offsets, groups, topics and partitions should not be empty")
 		}
 	}
 	if o.describeLogDirs {
 		if o.brokers == "" {
-			return errors.New("when list consumer offsets, groups, topics and partitions should not be empty")
+			return utils.UsageError("when describe log dirs, brokers should not be empty")
 		}
 	}
 	return nil
 }
 
-func (o *adminOptions) run(cmd *cobra.Command, args []string) {
-	err := o.validate()
+func (o *adminOptions) run(cmd *cobra.Command, args []string) error {
+	if !o.deleteRecords && !o.listConsumerGroups && !o.describeGroups && !o.deleteGroups &&
+		!o.listConsumerOffsets && !o.describeCluster && !o.describeLogDirs {
+		return cmd.Help()
+	}
+	if err := o.validate(); err != nil {
+		return err
+	}
+	ctx, cancel := o.client.NewContext(cmd.Context())
+	defer cancel()
+	return kafka.Wait(ctx, o.execute)
+}
+
+func (o *adminOptions) execute() (err error) {
+	config, err := o.client.NewConfig()
+	if err != nil {
+		return err
+	}
+	admin, err := kafka.NewAdmin(o.client.Brokers(), config)
 	if err != nil {
-		log.Info("admin flags validate failed", zap.Error(err))
-		return
+		return err
 	}
-	config := sarama.NewConfig()
-	servers := strings.Split(o.bootstrapServers, ",")
-	admin, err := kafka.NewAdmin(servers, config)
-	utils.CheckErr(err)
-	defer func() {
-		utils.CheckErr(admin.Close())
-	}()
-
-	if  o.deleteRecords {
+	defer utils.Close(admin, &err)
+
+	if o.deleteRecords {
 		topics := strings.Split(o.topics, ",")
 		_partitions := strings.Split(o.partitions, ",")
 		partitionOffsets := map[int32]int64{}
 		for _, p := range _partitions {
-			partition, err := strconv.ParseInt(p, 10, 64)
-			utils.CheckErr(err)
-			partitionOffsets[int32(partition)]=o.offset
+			partition, err := strconv.ParseInt(p, 10, 32)
+			if err != nil {
+				return utils.UsageError("invalid partition %q", p)
+			}
+			partitionOffsets[int32(partition)] = o.offset
 		}
 		for _, t := range topics {
-			utils.CheckErr(admin.DeleteRecords(t, partitionOffsets))
+			if err := admin.DeleteRecords(t, partitionOffsets); err != nil {
+				return err
+			}
 			log.Info("delete records success", zap.String("topic", t), zap.Any("offsetPartitions", partitionOffsets))
 		}
-	} else if o.listConsumerGroups{
+	} else if o.listConsumerGroups {
 		groups, err := admin.ListConsumerGroups()
-		utils.CheckErr(err)
+		if err != nil {
+			return err
+		}
 		utils.PrintConsumerGroups(groups)
 	} else if o.describeGroups {
 		groupDetail, err := admin.DescribeConsumerGroups(strings.Split(o.groups, ","))
-		utils.CheckErr(err)
+		if err != nil {
+			return err
+		}
 		for _, g := range groupDetail {
-			utils.PrintGroupDetail(g)
+			if g.Err != sarama.ErrNoError {
+				return fmt.Errorf("describe group %s: %w", g.GroupId, g.Err)
+			}
 		}
-	} else if o.deleteGroups{
+		utils.PrintGroupDetails(groupDetail)
+	} else if o.deleteGroups {
 		groups := strings.Split(o.groups, ",")
 		for _, g := range groups {
-			utils.CheckErr(admin.DeleteConsumerGroup(g))
+			if err := admin.DeleteConsumerGroup(g); err != nil {
+				return fmt.Errorf("delete group %s: %w", g, err)
+			}
 			log.Info("Delete consumer group success", zap.String("group", g))
 		}
-	}else if o.listConsumerOffsets{
+	} else if o.listConsumerOffsets {
 		groups := strings.Split(o.groups, ",")
 		topics := strings.Split(o.topics, ",")
 		_partitions := strings.Split(o.partitions, ",")
 		var partitions []int32
 		for _, p := range _partitions {
-			partition, _ := strconv.Atoi(p)
+			partition, err := strconv.Atoi(p)
+			if err != nil {
+				return utils.UsageError("invalid partition %q", p)
+			}
 			partitions = append(partitions, int32(partition))
 		}
 		topicPartitions := map[string][]int32{}
 		for _, t := range topics {
 			topicPartitions[t] = partitions
 		}
+		offsets := map[string]*sarama.OffsetFetchResponse{}
 		for _, g := range groups {
 			res, err := admin.ListConsumerGroupOffsets(g, topicPartitions)
-			utils.CheckErr(err)
-			utils.PrintConsumerGroupOffsets(g, res)
+			if err != nil {
+				return err
+			}
+			offsets[g] = res
 		}
-	}else if o.describeCluster{
+		utils.PrintConsumerGroupOffsets(offsets)
+	} else if o.describeCluster {
 		brokers, controllerID, err := admin.DescribeCluster()
-		utils.CheckErr(err)
+		if err != nil {
+			return err
+		}
 		utils.PrintCluster(controllerID, brokers)
-	}else if o.describeLogDirs{
+	} else if o.describeLogDirs {
 		_brokers := strings.Split(o.brokers, ",")
 		var brokers []int32
 		for _, b := range _brokers {
 			broker, err := strconv.Atoi(b)
-			utils.CheckErr(err)
+			if err != nil {
+				return utils.UsageError("invalid broker id %q", b)
+			}
 			brokers = append(brokers, int32(broker))
 		}
 
 		res, err := admin.DescribeLogDirs(brokers)
-		utils.CheckErr(err)
+		if err != nil {
+			return err
+		}
 		utils.PrintLogDirs(res)
-	}else {
-		cmd.Help()
 	}
+	return nil
 }
 
-func NewCmdAdmin() *cobra.Command {
-	o := newAdminOptions()
+func NewCmdAdmin(clientOptions *kafka.ClientOptions) *cobra.Command {
+	o := newAdminOptions(clientOptions)
 
 	cmd := &cobra.Command{
 		Use:     "admin",
 		Short:   "Kafka admin operations",
 		Long:    "Admin operations. delete records, list consumer groups, describe groups, list group offsets, delete groups, describe cluster, describe log dirs and etc",
 		Example: adminExample,
-		Run:     o.run,
+		RunE:    o.run,
 	}
-	cmd.Flags().StringVarP(&o.bootstrapServers, "bootstrap-server", "b", "localhost:9092", "The Kafka server to connect to.more than one should be separated by commas")
 	cmd.Flags().BoolVar(&o.deleteRecords, "delete-records", o.deleteRecords, "Delete record, when specified, topics, partitions and offset should also specified")
 	cmd.Flags().BoolVar(&o.listConsumerGroups, "list-consumer-groups", o.listConsumerGroups, "List all consumer groups")
 	cmd.Flags().BoolVar(&o.describeGroups, "describe-groups", o.describeGroups, "Describe a certain consumer group,when specified, groups should also specified")
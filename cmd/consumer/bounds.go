@@ -0,0 +1,291 @@
+package consumer
+
+import (
+	"github.com/Shopify/sarama"
+	"github.com/spf13/pflag"
+	"github.com/thimico/kafka-cli/filter"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/log"
+	"github.com/thimico/kafka-cli/utils"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// boundOptions are the flags which let consumer and consumerg stop on their own.
+type boundOptions struct {
+	maxMessages int64
+	untilOffset int64
+	untilTime   string
+	exitOnEOF   bool
+
+	until time.Time
+}
+
+func (o *boundOptions) addFlags(flags *pflag.FlagSet) {
+	flags.Int64Var(&o.maxMessages, "max-messages", 0, "Exit after this many messages, with --filter this many matching messages (default no limit)")
+	flags.Int64Var(&o.untilOffset, "until-offset", -1, "Stop consuming a partition at this offset, exclusive, exit once every partition reached it, -1 means no limit")
+	flags.StringVar(&o.untilTime, "until-time", "", "Stop consuming a partition at the first message at or after this RFC3339 or relative time like +10m, exit once every partition reached it")
+	flags.BoolVar(&o.exitOnEOF, "exit-on-eof", false, "Exit once every partition reached the high water mark it had at start")
+}
+
+func (o *boundOptions) complete() error {
+	if o.maxMessages < 0 {
+		return utils.UsageError("--max-messages should not be negative")
+	}
+	if o.untilTime != "" {
+		until, err := kafka.ParseTime(o.untilTime, time.Now())
+		if err != nil {
+			return utils.UsageError("--until-time: %s", err)
+		}
+		o.until = until
+	}
+	return nil
+}
+
+// idleCheckInterval is how often partitions without messages are checked
+// for their end, see bounds.idle.
+const idleCheckInterval = time.Second
+
+// partitionBounded reports whether partitions end on their own.
+func (o *boundOptions) partitionBounded() bool {
+	return o.exitOnEOF || o.untilOffset >= 0 || !o.until.IsZero()
+}
+
+// bounds tracks the consumed messages and partitions against the bound
+// options. It is shared by the claims of a consumer group, which run in
+// parallel.
+type bounds struct {
+	opts *boundOptions
+
+	// skipped is kafka.OffsetsSkipped, tests replace it
+	skipped func(client sarama.Client, tp kafka.TopicPartition, from, to int64) (bool, error)
+
+	mu       sync.Mutex
+	consumed int64
+	hwm      map[kafka.TopicPartition]int64
+	// next is the offset after the last message delivered of a partition,
+	// or its start offset
+	next map[kafka.TopicPartition]int64
+	done map[kafka.TopicPartition]bool
+	// quiet counts the idle checks in a row a partition got no message
+	quiet    map[kafka.TopicPartition]int
+	assigned []kafka.TopicPartition
+	finished bool
+	finish   chan struct{}
+}
+
+func newBounds(opts *boundOptions) *bounds {
+	return &bounds{
+		opts:    opts,
+		skipped: kafka.OffsetsSkipped,
+		hwm:     map[kafka.TopicPartition]int64{},
+		next:    map[kafka.TopicPartition]int64{},
+		done:    map[kafka.TopicPartition]bool{},
+		quiet:   map[kafka.TopicPartition]int{},
+		finish:  make(chan struct{}),
+	}
+}
+
+// Done is closed once the consumer should exit.
+func (b *bounds) Done() <-chan struct{} {
+	return b.finish
+}
+
+// assign sets the partitions which have to end before the consumer exits.
+func (b *bounds) assign(tps []kafka.TopicPartition) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.assigned = tps
+	b.checkPartitions()
+}
+
+// start is called when a partition starts consuming at offset, which may be
+// sarama.OffsetOldest or sarama.OffsetNewest. The high water mark of a
+// partition is captured only the first time it starts.
+func (b *bounds) start(client sarama.Client, tp kafka.TopicPartition, offset int64) error {
+	if !b.opts.partitionBounded() {
+		return nil
+	}
+	b.mu.Lock()
+	hwm, captured := b.hwm[tp]
+	b.mu.Unlock()
+	if !captured && b.opts.exitOnEOF {
+		var err error
+		if hwm, err = client.GetOffset(tp.Topic, tp.Partition, sarama.OffsetNewest); err != nil {
+			return err
+		}
+	}
+	if offset == sarama.OffsetNewest && b.opts.exitOnEOF {
+		offset = hwm
+	} else if offset < 0 {
+		var err error
+		if offset, err = client.GetOffset(tp.Topic, tp.Partition, offset); err != nil {
+			return err
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !captured {
+		b.hwm[tp] = hwm
+	}
+	b.next[tp] = offset
+	if (b.opts.exitOnEOF && offset >= b.hwm[tp]) || (b.opts.untilOffset >= 0 && offset >= b.opts.untilOffset) {
+		b.partitionDone(tp)
+	}
+	return nil
+}
+
+// accept reports whether msg is within the bounds and should be processed.
+func (b *bounds) accept(msg *sarama.ConsumerMessage) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tp := kafka.TopicPartition{Topic: msg.Topic, Partition: msg.Partition}
+	if b.finished || b.done[tp] {
+		return false
+	}
+	b.quiet[tp] = 0
+	b.next[tp] = msg.Offset + 1
+	if b.opts.untilOffset >= 0 && msg.Offset >= b.opts.untilOffset {
+		b.partitionDone(tp)
+		return false
+	}
+	if !b.opts.until.IsZero() && !msg.Timestamp.IsZero() && !msg.Timestamp.Before(b.opts.until) {
+		b.partitionDone(tp)
+		return false
+	}
+	return true
+}
+
+// processed records an accepted message, only matched messages count towards
+// --max-messages. It returns false when the consumer finished in between, the
+// message should then be neither output nor committed.
+func (b *bounds) processed(msg *sarama.ConsumerMessage, matched bool) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.finished {
+		return false
+	}
+	if matched {
+		b.consumed++
+		if b.opts.maxMessages > 0 && b.consumed >= b.opts.maxMessages {
+			b.stop("max messages")
+		}
+	}
+	tp := kafka.TopicPartition{Topic: msg.Topic, Partition: msg.Partition}
+	if b.opts.exitOnEOF && msg.Offset+1 >= b.hwm[tp] {
+		b.partitionDone(tp)
+	}
+	return true
+}
+
+// idle is called every idleCheckInterval with the high water mark of the
+// last fetch of tp. sarama does not tell how far a partition consumer
+// fetched, so once a partition got no message for two checks in a row the
+// offsets from the last delivered message to the bound are fetched: the
+// partition ends when they hold nothing the consumer delivers, such as the
+// transaction markers at the end of a partition. A partition consumer which
+// stalls below the bound does not end.
+func (b *bounds) idle(client sarama.Client, tp kafka.TopicPartition, highWaterMark int64) {
+	if !b.opts.partitionBounded() {
+		return
+	}
+	b.mu.Lock()
+	// no fetch has completed yet while the high water mark is 0
+	if b.finished || b.done[tp] || highWaterMark <= 0 {
+		b.mu.Unlock()
+		return
+	}
+	// the first quiet check may race with a fetch which is not delivered yet
+	if b.quiet[tp]++; b.quiet[tp] < 2 {
+		b.mu.Unlock()
+		return
+	}
+	next, started := b.next[tp]
+	end, bounded := b.end(tp, highWaterMark)
+	b.mu.Unlock()
+	if !started || !bounded || highWaterMark < end {
+		// the broker does not have the offsets up to the bound yet
+		return
+	}
+	if next < end {
+		skipped, err := b.skipped(client, tp, next, end)
+		if err != nil {
+			log.Warn("Check the end of a partition", zap.String("topic", tp.Topic), zap.Int32("partition", tp.Partition), zap.Error(err))
+			return
+		}
+		if !skipped {
+			return
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// a message may have arrived while fetching, it was processed then
+	if b.next[tp] == next {
+		b.partitionDone(tp)
+	}
+}
+
+// end returns the offset tp ends at, the nearest of its bounds. A time bound
+// which passed ends it at the high water mark, the messages before were
+// produced before the bound or end the partition by their timestamp.
+func (b *bounds) end(tp kafka.TopicPartition, highWaterMark int64) (end int64, bounded bool) {
+	bound := func(offset int64) {
+		if !bounded || offset < end {
+			end, bounded = offset, true
+		}
+	}
+	if hwm, captured := b.hwm[tp]; b.opts.exitOnEOF && captured {
+		bound(hwm)
+	}
+	if b.opts.untilOffset >= 0 {
+		bound(b.opts.untilOffset)
+	}
+	if !b.opts.until.IsZero() && !time.Now().Before(b.opts.until) {
+		bound(highWaterMark)
+	}
+	return end, bounded
+}
+
+func (b *bounds) partitionDone(tp kafka.TopicPartition) {
+	if !b.done[tp] {
+		b.done[tp] = true
+		log.Info("Partition reached its end", zap.String("topic", tp.Topic), zap.Int32("partition", tp.Partition))
+	}
+	b.checkPartitions()
+}
+
+func (b *bounds) checkPartitions() {
+	if !b.opts.partitionBounded() || len(b.assigned) == 0 {
+		return
+	}
+	for _, tp := range b.assigned {
+		if !b.done[tp] {
+			return
+		}
+	}
+	b.stop("all partitions reached their end")
+}
+
+func (b *bounds) stop(reason string) {
+	if b.finished {
+		return
+	}
+	b.finished = true
+	log.Info("Stop consuming", zap.String("reason", reason), zap.Int64("messages", b.consumed))
+	close(b.finish)
+}
+
+func addFilterFlag(flags *pflag.FlagSet, filters *[]string) {
+	flags.StringArrayVar(filters, "filter", nil, `Only output messages matching this expression, e.g. 'key =~ "^user-" && headers.source == "web"' or 'value.status == "FAILED" && value.amount > 100', can be repeated`)
+}
+
+// logFilterSummary logs how many messages the filter scanned and matched.
+func logFilterSummary(f *filter.Filter) {
+	if f == nil {
+		return
+	}
+	scanned, matched := f.Counts()
+	log.Info("Filter summary", zap.Int64("scanned", scanned), zap.Int64("matched", matched))
+}
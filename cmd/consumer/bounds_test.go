@@ -0,0 +1,123 @@
+package consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/kafka"
+)
+
+func finished(b *bounds) bool {
+	select {
+	case <-b.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestBoundsIdleReachesEnd(t *testing.T) {
+	tp := kafka.TopicPartition{Topic: "t", Partition: 0}
+	tests := []struct {
+		name string
+		opts boundOptions
+		// hwm is the high water mark captured at start
+		hwm int64
+		// fetched is the high water mark of the last fetch
+		fetched int64
+		// skipped is whether the offsets after message 8 up to the bound
+		// hold nothing to deliver
+		skipped bool
+		// checked is the range of offsets checked, if any
+		checked [2]int64
+		done    bool
+	}{
+		// offset 9 is the commit marker of a transaction, never delivered
+		{"exit on eof behind a control record", boundOptions{untilOffset: -1, exitOnEOF: true}, 10, 10, true, [2]int64{9, 10}, true},
+		{"exit on eof grown high water mark", boundOptions{untilOffset: -1, exitOnEOF: true}, 10, 15, true, [2]int64{9, 10}, true},
+		{"exit on eof not fetched yet", boundOptions{untilOffset: -1, exitOnEOF: true}, 10, 0, true, [2]int64{}, false},
+		// the partition consumer stalls, a failover or a slow fetch
+		{"exit on eof stalled below the bound", boundOptions{untilOffset: -1, exitOnEOF: true}, 10, 10, false, [2]int64{9, 10}, false},
+		{"until offset behind a control record", boundOptions{untilOffset: 10}, 0, 10, true, [2]int64{9, 10}, true},
+		{"until offset stalled below the bound", boundOptions{untilOffset: 10}, 0, 12, false, [2]int64{9, 10}, false},
+		{"until offset not produced yet", boundOptions{untilOffset: 20}, 0, 10, true, [2]int64{}, false},
+		{"nearest bound", boundOptions{untilOffset: 10, exitOnEOF: true}, 12, 12, true, [2]int64{9, 10}, true},
+		{"until time passed", boundOptions{untilOffset: -1, until: time.Now().Add(-time.Minute)}, 0, 10, true, [2]int64{9, 10}, true},
+		{"until time passed stalled", boundOptions{untilOffset: -1, until: time.Now().Add(-time.Minute)}, 0, 10, false, [2]int64{9, 10}, false},
+		{"until time passed all delivered", boundOptions{untilOffset: -1, until: time.Now().Add(-time.Minute)}, 0, 9, false, [2]int64{}, true},
+		{"until time ahead", boundOptions{untilOffset: -1, until: time.Now().Add(time.Hour)}, 0, 10, true, [2]int64{}, false},
+		{"unbounded", boundOptions{untilOffset: -1}, 0, 10, true, [2]int64{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBounds(&tt.opts)
+			var checked [2]int64
+			b.skipped = func(client sarama.Client, got kafka.TopicPartition, from, to int64) (bool, error) {
+				if got != tp {
+					t.Errorf("checked partition %s", got)
+				}
+				checked = [2]int64{from, to}
+				return tt.skipped, nil
+			}
+			b.hwm[tp] = tt.hwm
+			b.assign([]kafka.TopicPartition{tp})
+			msg := &sarama.ConsumerMessage{Topic: tp.Topic, Partition: tp.Partition, Offset: 8, Timestamp: time.Now().Add(-time.Hour)}
+			if !b.accept(msg) || !b.processed(msg, true) {
+				t.Fatal("message 8 should be processed")
+			}
+			if finished(b) {
+				t.Fatal("finished on message 8")
+			}
+			b.idle(nil, tp, tt.fetched)
+			if finished(b) || checked != [2]int64{} {
+				t.Fatal("checked on the first idle check")
+			}
+			b.idle(nil, tp, tt.fetched)
+			if got := finished(b); got != tt.done {
+				t.Errorf("finished after the second idle check = %v, want %v", got, tt.done)
+			}
+			if checked != tt.checked {
+				t.Errorf("checked offsets %v, want %v", checked, tt.checked)
+			}
+		})
+	}
+}
+
+func TestBoundsIdleResetByMessage(t *testing.T) {
+	tp := kafka.TopicPartition{Topic: "t", Partition: 0}
+	b := newBounds(&boundOptions{untilOffset: -1, exitOnEOF: true})
+	b.skipped = func(sarama.Client, kafka.TopicPartition, int64, int64) (bool, error) { return true, nil }
+	b.hwm[tp] = 10
+	b.assign([]kafka.TopicPartition{tp})
+
+	b.idle(nil, tp, 10)
+	msg := &sarama.ConsumerMessage{Topic: tp.Topic, Partition: tp.Partition, Offset: 7}
+	b.accept(msg)
+	b.processed(msg, true)
+	b.idle(nil, tp, 10)
+	if finished(b) {
+		t.Fatal("a message in between should restart the idle checks")
+	}
+	b.idle(nil, tp, 10)
+	if !finished(b) {
+		t.Fatal("not finished after two idle checks in a row")
+	}
+}
+
+func TestBoundsIdleCheckFails(t *testing.T) {
+	tp := kafka.TopicPartition{Topic: "t", Partition: 0}
+	b := newBounds(&boundOptions{untilOffset: -1, exitOnEOF: true})
+	b.skipped = func(sarama.Client, kafka.TopicPartition, int64, int64) (bool, error) {
+		return false, sarama.ErrNotLeaderForPartition
+	}
+	b.hwm[tp] = 10
+	b.next[tp] = 4
+	b.assign([]kafka.TopicPartition{tp})
+	for i := 0; i < 5; i++ {
+		b.idle(nil, tp, 10)
+	}
+	if finished(b) {
+		t.Fatal("finished although the offsets after 4 could not be checked")
+	}
+}
@@ -1,71 +1,214 @@
 package consumer
 
 import (
+	"errors"
+	"fmt"
 	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/filter"
 	"github.com/thimico/kafka-cli/kafka"
 	"github.com/thimico/kafka-cli/log"
 	"github.com/thimico/kafka-cli/utils"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var consumerExample = `
-# Consume msgs which start with given topic, partition and offset.
-    ./kafka-cli consumer --bootstrapServers=localhost:9092 --topic=singed --offset=1 --partition=1
+# Consume new messages of every partition of a topic
+    ./kafka-cli consumer --bootstrap-servers=localhost:9092 --topic=singed
+
+# Consume partitions 0, 3 and 5 of two topics from the oldest message
+    ./kafka-cli consumer --topic=singed,test --partitions=0,3,5 --offset=oldest
+
+# Start partition 0 at offset 100, partition 1 at the oldest and every other partition at the newest message
+    ./kafka-cli consumer --topic=singed --offset=0=100,1=oldest
+
+# Consume the messages of the last 15 minutes, or since 09:00 UTC
+    ./kafka-cli consumer --topic=singed --from-time=-15m
+    ./kafka-cli consumer --topic=singed --from-time=2021-02-04T09:00:00Z
+
+# Find the failed orders above 100 of the last hour
+    ./kafka-cli consumer --topic=orders --from-time=-1h --exit-on-eof --filter='value.status == "FAILED" && value.amount > 100'
+
+# Decode avro values with the schemas of a Schema Registry
+    ./kafka-cli consumer --topic=orders --value-format=avro --schema-registry-url=http://localhost:8081
+
+# Export everything the topic holds right now and exit
+    ./kafka-cli consumer --topic=singed --offset=oldest --exit-on-eof -o ndjson > singed.ndjson
 `
 
 type consumerOptions struct {
-	bootstrapServers string
-	topic            string
-	partition        int32
-	offset           int64
+	client *kafka.ClientOptions
+
+	topic      string
+	partitions string
+	partition  int32
+	offset     string
+	fromTime   string
+	filters    []string
+	bounds     boundOptions
+	formats    formatOptions
 }
 
-func newConsumerOptions() *consumerOptions {
-	return &consumerOptions{}
+func newConsumerOptions(clientOptions *kafka.ClientOptions) *consumerOptions {
+	return &consumerOptions{client: clientOptions}
 }
 
-func (o *consumerOptions) run(cmd *cobra.Command, args []string) {
-	if o.topic != "" {
-		config := sarama.NewConfig()
-		c, err := kafka.NewConsumer(strings.Split(o.bootstrapServers, ","), config)
-		utils.CheckErr(err)
-		defer func() {
-			utils.CheckErr(c.Close())
-		}()
-		pc, err := c.ConsumePartition(o.topic, o.partition, o.offset)
-		utils.CheckErr(err)
-		defer func() {
-			utils.CheckErr(pc.Close())
-		}()
-		for {
-			select {
-			case msg := <-pc.Messages():
-				utils.PrintConsumerMessage(msg)
-			case err := <-pc.Errors():
-				log.Info("partition consumer", zap.Error(err))
-			default:
-				//do nothing
+func (o *consumerOptions) run(cmd *cobra.Command, args []string) (err error) {
+	if o.topic == "" {
+		return cmd.Help()
+	}
+	if cmd.Flags().Changed("partition") {
+		o.partitions = strconv.Itoa(int(o.partition))
+	}
+	partitions, err := kafka.ParsePartitions(o.partitions)
+	if err != nil {
+		return utils.UsageError("%s", err)
+	}
+	offsets, err := kafka.ParseStartOffsets(o.offset)
+	if err != nil {
+		return utils.UsageError("%s", err)
+	}
+	var from time.Time
+	if o.fromTime != "" {
+		if cmd.Flags().Changed("offset") {
+			return utils.UsageError("--from-time and --offset are mutually exclusive")
+		}
+		if from, err = kafka.ParseTime(o.fromTime, time.Now()); err != nil {
+			return utils.UsageError("--from-time: %s", err)
+		}
+	}
+	if err := o.bounds.complete(); err != nil {
+		return err
+	}
+	f, err := filter.New(o.filters)
+	if err != nil {
+		return utils.UsageError("%s", err)
+	}
+	d, err := o.formats.decoders(o.client)
+	if err != nil {
+		return err
+	}
+	defer logFilterSummary(f)
+	ctx, cancel := o.client.NewContext(cmd.Context())
+	defer cancel()
+	config, err := o.client.NewConfig()
+	if err != nil {
+		return err
+	}
+	config.Consumer.Return.Errors = true
+	var client sarama.Client
+	err = kafka.WaitOpen(ctx, func() (err error) {
+		client, err = kafka.NewClient(o.client.Brokers(), config)
+		return err
+	}, func() { client.Close() })
+	if err != nil {
+		return err
+	}
+	defer utils.Close(client, &err)
+	c, err := kafka.NewConsumerFromClient(client)
+	if err != nil {
+		return err
+	}
+	defer utils.Close(c, &err)
+	tps, err := kafka.ResolvePartitions(c, strings.Split(o.topic, ","), partitions)
+	if err != nil {
+		return err
+	}
+	start := offsets.Resolve(tps)
+	if !from.IsZero() {
+		if start, err = kafka.OffsetsForTime(client, tps, from); err != nil {
+			return err
+		}
+	}
+	b := newBounds(&o.bounds)
+	b.assign(tps)
+	for _, tp := range tps {
+		if err := b.start(client, tp, start[tp]); err != nil {
+			return err
+		}
+	}
+	select {
+	case <-b.Done():
+		return nil
+	default:
+	}
+	pcs, err := kafka.ConsumePartitions(c, start)
+	if err != nil {
+		return err
+	}
+	// closing drains the messages which are already fetched
+	defer utils.Close(pcs, &err)
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+	// stopped are the partitions whose consumer gave up, sarama does for an
+	// offset out of range, e.g. deleted by retention
+	stopped := map[kafka.TopicPartition]bool{}
+	var firstStopped error
+	stoppedErr := func() error {
+		if len(stopped) == 0 {
+			return nil
+		}
+		return utils.Classify(fmt.Errorf("%d of %d partitions stopped consuming, the first: %w", len(stopped), len(tps), firstStopped))
+	}
+	for {
+		select {
+		case msg := <-pcs.Messages():
+			if !b.accept(msg) {
+				continue
+			}
+			m, ok := decode(d, msg)
+			matched := ok && f.Match(m)
+			if b.processed(msg, matched) && matched {
+				utils.PrintConsumerMessage(m)
+			}
+		case err := <-pcs.Errors():
+			tp := kafka.TopicPartition{Topic: err.Topic, Partition: err.Partition}
+			if !errors.Is(err.Err, sarama.ErrOffsetOutOfRange) {
+				// sarama retries the partition
+				log.Warn("partition consumer", zap.String("topic", err.Topic), zap.Int32("partition", err.Partition), zap.Error(err.Err))
+				continue
+			}
+			log.Error("Partition stopped consuming", zap.String("topic", err.Topic), zap.Int32("partition", err.Partition), zap.Error(err.Err))
+			if firstStopped == nil {
+				firstStopped = fmt.Errorf("%s: %w", tp, err.Err)
+			}
+			stopped[tp] = true
+			// a stopped partition never reaches its bound
+			if o.bounds.partitionBounded() || len(stopped) == len(tps) {
+				return stoppedErr()
+			}
+		case <-ticker.C:
+			for _, tp := range tps {
+				b.idle(client, tp, pcs.Consumer(tp).HighWaterMarkOffset())
 			}
+		case <-b.Done():
+			return stoppedErr()
+		case <-ctx.Done():
+			return stoppedErr()
 		}
-	} else {
-		cmd.Help()
 	}
 }
 
-func NewCmdConsumer() *cobra.Command {
-	o := newConsumerOptions()
+func NewCmdConsumer(clientOptions *kafka.ClientOptions) *cobra.Command {
+	o := newConsumerOptions(clientOptions)
 	cmd := &cobra.Command{
 		Use:     "consumer",
-		Short:   "Consume kafka message with given topic and partition",
-		Long:    "Consume kafka message with given topic and partition, if you want to consume all partition, please refer to use consumerg",
+		Short:   "Consume kafka message with given topics and partitions",
+		Long:    "Consume kafka message with given topics and partitions without a consumer group, nothing is committed, if you want to consume with a group, please refer to use consumerg",
 		Example: consumerExample,
-		Run:     o.run,
+		RunE:    o.run,
 	}
-	cmd.Flags().StringVarP(&o.bootstrapServers, "bootstrap-servers", "b", "localhost:9092", "The Kafka server to connect to.more than one should be separated by commas")
 	cmd.Flags().StringVar(&o.topic, "topic", o.topic, "REQUIRED: The topics to consume,more than one should be separated by commas")
-	cmd.Flags().Int32Var(&o.partition, "partition", 0, "The partition to consume (default 0)")
-	cmd.Flags().Int64Var(&o.offset, "offset", sarama.OffsetNewest, "Which offset to consume start with, -2 means oldest, -1 means newest")
+	cmd.Flags().StringVar(&o.partitions, "partitions", kafka.PartitionsAll, "The partitions to consume, all or a list like 0,3,5")
+	cmd.Flags().Int32Var(&o.partition, "partition", 0, "The partition to consume")
+	cmd.Flags().MarkDeprecated("partition", "use --partitions instead")
+	cmd.Flags().StringVar(&o.offset, "offset", "newest", "Which offset to consume start with, oldest (-2), newest (-1) or an offset, per partition as 0=100,1=oldest")
+	cmd.Flags().StringVar(&o.fromTime, "from-time", "", "Start every partition at the first message at or after this RFC3339 or relative time like -15m, needs kafka 0.10.1 or later")
+	o.bounds.addFlags(cmd.Flags())
+	addFilterFlag(cmd.Flags(), &o.filters)
+	o.formats.addFlags(cmd.Flags())
 	return cmd
 }
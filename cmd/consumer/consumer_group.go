@@ -1,72 +1,410 @@
 package consumer
 
 import (
+	"context"
+	"fmt"
 	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/filter"
 	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/log"
+	"github.com/thimico/kafka-cli/serde"
 	"github.com/thimico/kafka-cli/utils"
 	"github.com/spf13/cobra"
-	"golang.org/x/net/context"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+	"regexp"
 	"strings"
+	"time"
 )
 
 var (
 	consumergExample = `
 # Consume kafka messages of set of topic which certain group id, and will print those message in terminal
-	kafka-cli consume -t test,singed -c default -b localhost:9092
+	kafka-cli consumerg --topics=test,singed --group-id=default -b localhost:9092
+
+# Start a new group at the messages of the last hour
+	kafka-cli consumerg --topics=singed --group-id=audit --from-time=-1h
+
+# Consume until the group caught up with the end of every assigned partition, then commit and exit
+	kafka-cli consumerg --topics=singed --group-id=default --exit-on-eof
+
+# Debug a flapping group, the rebalances are logged, the lag of every partition every 30 seconds
+	kafka-cli consumerg --topics=singed --group-id=billing --commit=none --stats-interval=30s
+
+# Peek at the new messages of a real group without moving its offsets, this joins the group:
+# its live members are rebalanced and lose partitions to the peek while it runs
+	kafka-cli consumerg --topics=singed --group-id=billing --commit=none --initial-offset=newest
+
+# Consume every orders topic, topics created later are picked up within a minute
+	kafka-cli consumerg --topic-pattern='orders\..*' --group-id=orders-audit --topic-refresh-interval=1m
+
+# Only see committed transactions, committing synchronously after every fetched batch
+	kafka-cli consumerg --topics=singed --group-id=audit --commit=manual --isolation-level=read_committed --kafka-version=2.6.0
 		`
 )
 
+// The commit modes of consumerg.
+const (
+	commitAuto   = "auto"
+	commitManual = "manual"
+	commitNone   = "none"
+)
+
 type consumerGOptions struct {
-	bootstrapServers string
-	groupID          string
-	topics           string
+	client *kafka.ClientOptions
+
+	groupID  string
+	topics   string
+	fromTime string
+	filters  []string
+	bounds   boundOptions
+	formats  formatOptions
+
+	topicPattern   string
+	topicRefresh   time.Duration
+	internalTopics bool
+
+	commit            string
+	initialOffset     string
+	isolationLevel    string
+	balanceStrategy   string
+	sessionTimeout    time.Duration
+	heartbeatInterval time.Duration
+	statsInterval     time.Duration
+
+	from         time.Time
+	saramaClient sarama.Client
+	b            *bounds
+	f            *filter.Filter
+	d            *serde.Decoders
+	stats        *groupStats
+	// joinStart is when the current rebalance started
+	joinStart time.Time
 }
 
-func newConsumerGOptions() *consumerGOptions {
-	return &consumerGOptions{}
+func newConsumerGOptions(clientOptions *kafka.ClientOptions) *consumerGOptions {
+	return &consumerGOptions{client: clientOptions}
 }
 
-func (o *consumerGOptions) Setup(_ sarama.ConsumerGroupSession) error   { return nil }
-func (o *consumerGOptions) Cleanup(_ sarama.ConsumerGroupSession) error { return nil }
-func (o *consumerGOptions) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for msg := range claim.Messages() {
-		utils.PrintConsumerMessage(msg)
-		sess.MarkMessage(msg, "")
+// Setup logs every generation the member joins with the partitions it
+// claimed and how long the rebalance took.
+func (o *consumerGOptions) Setup(sess sarama.ConsumerGroupSession) error {
+	var tps []kafka.TopicPartition
+	for topic, partitions := range sess.Claims() {
+		for _, p := range partitions {
+			tps = append(tps, kafka.TopicPartition{Topic: topic, Partition: p})
+		}
+	}
+	log.Info("Partitions assigned", zap.String("memberId", sess.MemberID()), zap.Int32("generationId", sess.GenerationID()),
+		zap.Any("claims", sess.Claims()), zap.Duration("rebalance", time.Since(o.joinStart)))
+	o.b.assign(tps)
+	if !o.from.IsZero() {
+		return o.seekNewPartitions(sess, tps)
 	}
 	return nil
 }
 
-func (o *consumerGOptions) run(cmd *cobra.Command, args []string) {
-	if o.topics != "" {
-		config := sarama.NewConfig()
-		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+// seekNewPartitions moves the partitions which have no committed offset yet
+// to --from-time, partitions with a committed offset keep it.
+func (o *consumerGOptions) seekNewPartitions(sess sarama.ConsumerGroupSession, tps []kafka.TopicPartition) error {
+	coordinator, err := o.saramaClient.Coordinator(o.groupID)
+	if err != nil {
+		return err
+	}
+	req := &sarama.OffsetFetchRequest{Version: 1, ConsumerGroup: o.groupID}
+	for _, tp := range tps {
+		req.AddPartition(tp.Topic, tp.Partition)
+	}
+	res, err := coordinator.FetchOffset(req)
+	if err != nil {
+		return err
+	}
+	var uncommitted []kafka.TopicPartition
+	for _, tp := range tps {
+		block := res.GetBlock(tp.Topic, tp.Partition)
+		if block == nil {
+			return fmt.Errorf("fetch offset of %s: %w", tp, sarama.ErrIncompleteResponse)
+		}
+		if block.Err != sarama.ErrNoError {
+			return fmt.Errorf("fetch offset of %s: %w", tp, block.Err)
+		}
+		if block.Offset < 0 {
+			uncommitted = append(uncommitted, tp)
+		}
+	}
+	offsets, err := kafka.OffsetsForTime(o.saramaClient, uncommitted, o.from)
+	if err != nil {
+		return err
+	}
+	for tp, offset := range offsets {
+		sess.MarkOffset(tp.Topic, tp.Partition, offset, "")
+	}
+	return nil
+}
+
+// Cleanup commits the marked offsets before the session ends, so a bounded
+// consumer exits with its offsets committed.
+func (o *consumerGOptions) Cleanup(sess sarama.ConsumerGroupSession) error {
+	o.joinStart = time.Now()
+	log.Info("Partitions revoked", zap.String("memberId", sess.MemberID()), zap.Int32("generationId", sess.GenerationID()),
+		zap.Any("claims", sess.Claims()))
+	o.stats.release(sess.Claims())
+	if o.commit != commitNone {
+		sess.Commit()
+	}
+	return nil
+}
+
+func (o *consumerGOptions) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	tp := kafka.TopicPartition{Topic: claim.Topic(), Partition: claim.Partition()}
+	if err := o.b.start(o.saramaClient, tp, claim.InitialOffset()); err != nil {
+		return err
+	}
+	offset := claim.InitialOffset()
+	if offset < 0 && o.statsInterval > 0 {
+		// resolve oldest or newest, so the lag is known before the first message
+		var err error
+		if offset, err = o.saramaClient.GetOffset(tp.Topic, tp.Partition, offset); err != nil {
+			return err
+		}
+	}
+	o.stats.claim(claim, offset)
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if !o.b.accept(msg) {
+				continue
+			}
+			o.stats.consumed(msg)
+			m, ok := decode(o.d, msg)
+			matched := ok && o.f.Match(m)
+			if !o.b.processed(msg, matched) {
+				continue
+			}
+			if matched {
+				utils.PrintConsumerMessage(m)
+			}
+			if o.commit == commitNone {
+				continue
+			}
+			sess.MarkMessage(msg, "")
+			if o.commit == commitManual && len(claim.Messages()) == 0 {
+				// every fetched message is processed, commit synchronously
+				sess.Commit()
+			}
+		case <-ticker.C:
+			o.b.idle(o.saramaClient, tp, claim.HighWaterMarkOffset())
+		case <-sess.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (o *consumerGOptions) run(cmd *cobra.Command, args []string) (err error) {
+	if o.topics == "" && o.topicPattern == "" {
+		return cmd.Help()
+	}
+	var pattern *regexp.Regexp
+	if o.topicPattern != "" {
+		if o.topics != "" {
+			return utils.UsageError("--topics and --topic-pattern are mutually exclusive")
+		}
+		if pattern, err = kafka.CompileTopicPattern(o.topicPattern); err != nil {
+			return utils.UsageError("--topic-pattern: %s", err)
+		}
+		if o.topicRefresh <= 0 {
+			return utils.UsageError("--topic-refresh-interval should be positive")
+		}
+	}
+	switch o.commit {
+	case commitAuto, commitManual, commitNone:
+	default:
+		return utils.UsageError("invalid --commit %q, should be auto, manual or none", o.commit)
+	}
+	if o.fromTime != "" {
+		if o.commit == commitNone {
+			return utils.UsageError("--from-time moves the offsets of the group, it can not be used with --commit=none")
+		}
+		if o.from, err = kafka.ParseTime(o.fromTime, time.Now()); err != nil {
+			return utils.UsageError("--from-time: %s", err)
+		}
+	}
+	if err := o.bounds.complete(); err != nil {
+		return err
+	}
+	if o.commit == commitNone {
+		log.Warn("--commit=none joins the group as a member, its live members are rebalanced and lose partitions to this consumer while it runs",
+			zap.String("group", o.groupID))
+	}
+	if o.f, err = filter.New(o.filters); err != nil {
+		return utils.UsageError("%s", err)
+	}
+	if o.d, err = o.formats.decoders(o.client); err != nil {
+		return err
+	}
+	defer logFilterSummary(o.f)
+	ctx, cancel := o.client.NewContext(cmd.Context())
+	defer cancel()
+	config, err := o.client.NewConfig()
+	if err != nil {
+		return err
+	}
+	if err := o.applyGroupConfig(cmd.Flags(), config); err != nil {
+		return err
+	}
+	config.Consumer.Return.Errors = true
 
-		c, err := kafka.NewConsumerGroup(strings.Split(o.bootstrapServers, ","), o.groupID, config)
-		utils.CheckErr(err)
-		defer func() {
-			utils.CheckErr(c.Close())
-		}()
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-		err = c.Consume(ctx, strings.Split(o.topics, ","), o)
-		utils.CheckErr(err)
-	} else {
-		cmd.Help()
+	err = kafka.WaitOpen(ctx, func() (err error) {
+		o.saramaClient, err = kafka.NewClient(o.client.Brokers(), config)
+		return err
+	}, func() { o.saramaClient.Close() })
+	if err != nil {
+		return err
+	}
+	// the consumer group closes the client
+	c, err := sarama.NewConsumerGroupFromClient(o.groupID, o.saramaClient)
+	if err != nil {
+		o.saramaClient.Close()
+		return err
+	}
+	// closing leaves the group after the last session committed its offsets
+	defer utils.Close(c, &err)
+	go func() {
+		for err := range c.Errors() {
+			log.Info("consumer group", zap.Error(err))
+		}
+	}()
+	o.b = newBounds(&o.bounds)
+	go func() {
+		select {
+		case <-o.b.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	o.stats = newGroupStats()
+	if o.statsInterval > 0 {
+		go o.stats.run(ctx, o.statsInterval)
+	}
+	var watcher *topicWatcher
+	if pattern != nil {
+		watcher = newTopicWatcher(o.saramaClient, pattern, o.internalTopics)
+		if _, err := watcher.refresh(); err != nil {
+			return err
+		}
+		go watcher.run(ctx, o.topicRefresh)
 	}
+	topics := strings.Split(o.topics, ",")
+	o.joinStart = time.Now()
+	for {
+		if watcher != nil {
+			topics = watcher.Topics()
+		}
+		// Consume returns on every rebalance, it has to be called again to rejoin
+		if err := o.consume(ctx, c, topics, watcher); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// consume runs one session of the group. With --topic-pattern the session
+// also ends once the matched topics change, so the member rejoins with them.
+func (o *consumerGOptions) consume(ctx context.Context, c sarama.ConsumerGroup, topics []string, watcher *topicWatcher) error {
+	if watcher == nil {
+		return c.Consume(ctx, topics, o)
+	}
+	if len(topics) == 0 {
+		log.Info("No topic matches the pattern, waiting for one", zap.String("pattern", o.topicPattern))
+		select {
+		case <-watcher.changed:
+		case <-ctx.Done():
+		}
+		return nil
+	}
+	sessCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go func() {
+		select {
+		case <-watcher.changed:
+			stop()
+		case <-sessCtx.Done():
+		}
+	}()
+	return c.Consume(sessCtx, topics, o)
+}
+
+// applyGroupConfig applies the group flags, the tuning flags only when given,
+// so they do not override --client-property.
+func (o *consumerGOptions) applyGroupConfig(flags *pflag.FlagSet, config *sarama.Config) error {
+	switch o.initialOffset {
+	case "oldest":
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	case "newest":
+		config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	default:
+		return utils.UsageError("invalid --initial-offset %q, should be oldest or newest", o.initialOffset)
+	}
+	config.Consumer.Offsets.AutoCommit.Enable = o.commit == commitAuto
+	if flags.Changed("isolation-level") {
+		level, err := kafka.ParseIsolationLevel(o.isolationLevel)
+		if err != nil {
+			return utils.UsageError("--isolation-level %s", err)
+		}
+		config.Consumer.IsolationLevel = level
+	}
+	if flags.Changed("balance-strategy") {
+		strategy, err := kafka.ParseBalanceStrategy(o.balanceStrategy)
+		if err != nil {
+			return utils.UsageError("--balance-strategy %s", err)
+		}
+		config.Consumer.Group.Rebalance.Strategy = strategy
+	}
+	if flags.Changed("session-timeout") {
+		config.Consumer.Group.Session.Timeout = o.sessionTimeout
+	}
+	if flags.Changed("heartbeat-interval") {
+		config.Consumer.Group.Heartbeat.Interval = o.heartbeatInterval
+	}
+	if config.Consumer.Group.Heartbeat.Interval >= config.Consumer.Group.Session.Timeout {
+		return utils.UsageError("the heartbeat interval %s should be lower than the session timeout %s",
+			config.Consumer.Group.Heartbeat.Interval, config.Consumer.Group.Session.Timeout)
+	}
+	return nil
 }
 
-func NewCmdConsumeGroup() *cobra.Command {
-	o := newConsumerGOptions()
+func NewCmdConsumeGroup(clientOptions *kafka.ClientOptions) *cobra.Command {
+	o := newConsumerGOptions(clientOptions)
 	cmd := &cobra.Command{
 		Use:     "consumerg",
 		Short:   "Consume kafka message with given topics and group_id",
-		Long:    "Consume kafka message with given topics and group_id, and message will be auto committed",
+		Long:    "Consume kafka message with given topics and group_id, the offsets are committed as selected by --commit. Every mode, --commit=none too, joins the group as a member, which rebalances the live members of the group and takes partitions from them while it runs",
 		Example: consumergExample,
-		Run:     o.run,
+		RunE:    o.run,
 	}
 
-	cmd.Flags().StringVarP(&o.bootstrapServers, "bootstrap-servers", "b", "localhost:9092", "The Kafka server to connect to.more than one should be separated by commas")
 	cmd.Flags().StringVar(&o.topics, "topics", o.topics, "The topics to consume,more than one should be separated by commas")
+	cmd.Flags().StringVar(&o.topicPattern, "topic-pattern", "", "Consume every topic whose whole name matches this regular expression, e.g. 'orders\\..*', instead of --topics")
+	cmd.Flags().DurationVar(&o.topicRefresh, "topic-refresh-interval", 30*time.Second, "How often the topics matching --topic-pattern are looked up, the group rejoins when they changed")
+	cmd.Flags().BoolVar(&o.internalTopics, "include-internal-topics", false, "Let --topic-pattern match the internal topics of the brokers, like __consumer_offsets")
 	cmd.Flags().StringVar(&o.groupID, "group-id", "kafka-cli", "The consumer group ID")
+	cmd.Flags().StringVar(&o.fromTime, "from-time", "", "Start partitions without a committed offset at the first message at or after this RFC3339 or relative time like -15m, needs kafka 0.10.1 or later")
+	cmd.Flags().StringVar(&o.commit, "commit", commitAuto, "How offsets are committed: auto in the background, manual synchronously after every fetched batch, or none to never move the offsets of the group, none still joins the group and rebalances its live members")
+	cmd.Flags().StringVar(&o.initialOffset, "initial-offset", "oldest", "Where partitions without a committed offset start, oldest or newest")
+	cmd.Flags().StringVar(&o.isolationLevel, "isolation-level", "read_uncommitted", "read_committed skips aborted and open transactions, needs kafka 0.11 or later")
+	cmd.Flags().StringVar(&o.balanceStrategy, "balance-strategy", "range", "How partitions are assigned to the members, range, roundrobin or sticky")
+	cmd.Flags().DurationVar(&o.sessionTimeout, "session-timeout", 10*time.Second, "The member leaves the group when no heartbeat reaches the coordinator in this time")
+	cmd.Flags().DurationVar(&o.heartbeatInterval, "heartbeat-interval", 3*time.Second, "How often heartbeats are sent, should be lower than a third of --session-timeout")
+	cmd.Flags().DurationVar(&o.statsInterval, "stats-interval", 0, "Log the consumed messages in total and since the last log, offset and lag of every claimed partition at this interval, e.g. 30s (default off)")
+	o.bounds.addFlags(cmd.Flags())
+	addFilterFlag(cmd.Flags(), &o.filters)
+	o.formats.addFlags(cmd.Flags())
 	return cmd
 }
@@ -0,0 +1,49 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/utils"
+)
+
+func TestConsumerPartitionStopped(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	// retention deletes the offset to start at after the consumer started
+	fetch := &sarama.FetchResponse{Version: 4}
+	fetch.AddError("t", 0, sarama.ErrOffsetOutOfRange)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("t", 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).SetVersion(1).
+			SetOffset("t", 0, sarama.OffsetOldest, 100).
+			SetOffset("t", 0, sarama.OffsetNewest, 200),
+		"FetchRequest": sarama.NewMockWrapper(fetch),
+	})
+
+	for _, args := range [][]string{{"--offset=150"}, {"--offset=150", "--exit-on-eof"}} {
+		t.Run(args[len(args)-1], func(t *testing.T) {
+			cmd := NewCmdConsumer(&kafka.ClientOptions{BootstrapServers: broker.Addr(), KafkaVersion: "0.11.0.0", Timeout: 5 * time.Second})
+			cmd.SilenceUsage, cmd.SilenceErrors = true, true
+			cmd.SetArgs(append([]string{"--topic=t"}, args...))
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := cmd.ExecuteContext(ctx)
+			if !errors.Is(err, sarama.ErrOffsetOutOfRange) {
+				t.Fatalf("err = %v, want offset out of range", err)
+			}
+			if err.Error() != "1 of 1 partitions stopped consuming, the first: t/0: "+sarama.ErrOffsetOutOfRange.Error() {
+				t.Errorf("err = %v", err)
+			}
+			if code := utils.ExitCode(err); code != utils.KindKafka.ExitCode() {
+				t.Errorf("exit code = %d, want %d", code, utils.KindKafka.ExitCode())
+			}
+		})
+	}
+}
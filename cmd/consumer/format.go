@@ -0,0 +1,64 @@
+package consumer
+
+import (
+	"github.com/Shopify/sarama"
+	"github.com/spf13/pflag"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/log"
+	"github.com/thimico/kafka-cli/serde"
+	"github.com/thimico/kafka-cli/utils"
+	"go.uber.org/zap"
+	"strings"
+)
+
+// formatOptions are the flags which select how keys and values are decoded.
+type formatOptions struct {
+	keyFormat          string
+	valueFormat        string
+	headerFormat       string
+	protoDescriptorSet string
+	protoMessage       string
+	protoKeyMessage    string
+}
+
+func (o *formatOptions) addFlags(flags *pflag.FlagSet) {
+	formats := strings.Join(serde.Formats, ", ")
+	flags.StringVar(&o.keyFormat, "key-format", serde.FormatString, "The format of the message keys, one of "+formats)
+	flags.StringVar(&o.valueFormat, "value-format", serde.FormatString, "The format of the message values, one of "+formats+", avro needs --schema-registry-url, protobuf --proto-descriptor-set and --proto-message")
+	flags.StringVar(&o.headerFormat, "header-format", serde.FormatString, "The format of the header values, one of "+strings.Join(serde.PrimitiveFormats, ", "))
+	flags.StringVar(&o.protoDescriptorSet, "proto-descriptor-set", "", "The FileDescriptorSet of the protobuf format, built with protoc --include_imports --descriptor_set_out=file.pb")
+	flags.StringVar(&o.protoMessage, "proto-message", "", "The full name of the protobuf message type of the values, e.g. shop.v1.Order")
+	flags.StringVar(&o.protoKeyMessage, "proto-key-message", "", "The full name of the protobuf message type of the keys")
+}
+
+func (o *formatOptions) decoders(client *kafka.ClientOptions) (*serde.Decoders, error) {
+	c := &serde.Config{
+		Registry:          client.SchemaRegistry(),
+		ProtoKeyMessage:   o.protoKeyMessage,
+		ProtoValueMessage: o.protoMessage,
+	}
+	if o.protoDescriptorSet != "" {
+		files, err := serde.LoadDescriptorSet(o.protoDescriptorSet)
+		if err != nil {
+			return nil, utils.UsageError("--proto-descriptor-set: %s", err)
+		}
+		c.ProtoFiles = files
+	}
+	d, err := serde.NewDecoders(o.keyFormat, o.valueFormat, o.headerFormat, c)
+	if err != nil {
+		return nil, utils.UsageError("%s", err)
+	}
+	return d, nil
+}
+
+// decode decodes msg, a message which can not be decoded is logged and
+// treated as not matching.
+func decode(d *serde.Decoders, msg *sarama.ConsumerMessage) (*serde.Message, bool) {
+	m, err := d.Decode(msg)
+	if err != nil {
+		log.Warn("Skip message", zap.String("topic", msg.Topic), zap.Int32("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset), zap.Error(err))
+		return nil, false
+	}
+	return m, true
+}
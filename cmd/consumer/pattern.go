@@ -0,0 +1,103 @@
+package consumer
+
+import (
+	"context"
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/log"
+	"go.uber.org/zap"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// topicWatcher tracks the topics matching --topic-pattern.
+type topicWatcher struct {
+	client   sarama.Client
+	pattern  *regexp.Regexp
+	internal bool
+
+	mu     sync.Mutex
+	topics []string
+	// changed receives a value when the matched topics changed
+	changed chan struct{}
+}
+
+func newTopicWatcher(client sarama.Client, pattern *regexp.Regexp, internal bool) *topicWatcher {
+	return &topicWatcher{client: client, pattern: pattern, internal: internal, changed: make(chan struct{}, 1)}
+}
+
+// Topics returns the topics matched by the last refresh.
+func (w *topicWatcher) Topics() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.topics
+}
+
+// refresh matches the topics of the current metadata, it reports whether
+// they changed since the last refresh.
+func (w *topicWatcher) refresh() (bool, error) {
+	topics, err := kafka.MatchTopics(w.client, w.pattern, w.internal)
+	if err != nil {
+		return false, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	added, removed := diffTopics(w.topics, topics)
+	if len(added) == 0 && len(removed) == 0 {
+		return false, nil
+	}
+	log.Info("Topics matching the pattern changed", zap.String("pattern", w.pattern.String()),
+		zap.Strings("added", added), zap.Strings("removed", removed), zap.Int("topics", len(topics)))
+	w.topics = topics
+	return true, nil
+}
+
+// run refreshes the matched topics every interval until ctx is done, a
+// failed refresh keeps the topics matched before.
+func (w *topicWatcher) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			changed, err := w.refresh()
+			if err != nil {
+				log.Warn("Refresh topics matching the pattern", zap.Error(err))
+				continue
+			}
+			if changed {
+				select {
+				case w.changed <- struct{}{}:
+				default:
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// diffTopics returns the topics of b which are not in a and those of a which
+// are not in b.
+func diffTopics(a, b []string) (added, removed []string) {
+	in := func(topics []string, topic string) bool {
+		for _, t := range topics {
+			if t == topic {
+				return true
+			}
+		}
+		return false
+	}
+	for _, t := range b {
+		if !in(a, t) {
+			added = append(added, t)
+		}
+	}
+	for _, t := range a {
+		if !in(b, t) {
+			removed = append(removed, t)
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,155 @@
+package consumer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/kafka"
+)
+
+// topicBroker is a broker whose metadata holds a settable set of topics.
+type topicBroker struct {
+	t *testing.T
+	*sarama.MockBroker
+}
+
+func newTopicBroker(t *testing.T) *topicBroker {
+	b := &topicBroker{t: t, MockBroker: sarama.NewMockBroker(t, 1)}
+	b.setTopics()
+	return b
+}
+
+func (b *topicBroker) setTopics(topics ...string) {
+	metadata := sarama.NewMockMetadataResponse(b.t).SetBroker(b.Addr(), b.BrokerID())
+	for _, topic := range topics {
+		metadata.SetLeader(topic, 0, b.BrokerID())
+	}
+	b.SetHandlerByMap(map[string]sarama.MockResponse{"MetadataRequest": metadata})
+}
+
+func newTestWatcher(t *testing.T, b *topicBroker, pattern string, internal bool) *topicWatcher {
+	client, err := sarama.NewClient([]string{b.Addr()}, sarama.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	re, err := kafka.CompileTopicPattern(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newTopicWatcher(client, re, internal)
+}
+
+func TestTopicWatcherRefresh(t *testing.T) {
+	b := newTopicBroker(t)
+	defer b.Close()
+	w := newTestWatcher(t, b, `orders\..*`, false)
+
+	steps := []struct {
+		name    string
+		topics  []string
+		changed bool
+		want    []string
+	}{
+		{"nothing matches", []string{"payments", "orders"}, false, nil},
+		{"first match", []string{"orders.eu", "payments", "orders"}, true, []string{"orders.eu"}},
+		{"same topics", []string{"payments", "orders.eu"}, false, []string{"orders.eu"}},
+		{"unmatched topic created", []string{"orders.eu", "payments", "shipping"}, false, []string{"orders.eu"}},
+		{"matched topic created", []string{"orders.us", "orders.eu", "shipping"}, true, []string{"orders.eu", "orders.us"}},
+		{"matched topic deleted", []string{"orders.us", "shipping"}, true, []string{"orders.us"}},
+		// only the whole name matches
+		{"prefix and suffix", []string{"orders.us", "old-orders.eu", "orders"}, false, []string{"orders.us"}},
+	}
+	for _, s := range steps {
+		b.setTopics(s.topics...)
+		changed, err := w.refresh()
+		if err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if changed != s.changed || !reflect.DeepEqual(w.Topics(), s.want) {
+			t.Errorf("%s: changed %v with %v, want %v with %v", s.name, changed, w.Topics(), s.changed, s.want)
+		}
+	}
+}
+
+func TestTopicWatcherInternalTopics(t *testing.T) {
+	b := newTopicBroker(t)
+	defer b.Close()
+	b.setTopics("__consumer_offsets", "__transaction_state", "__orders", "orders")
+
+	for _, tt := range []struct {
+		internal bool
+		want     []string
+	}{
+		{false, []string{"__orders"}},
+		{true, []string{"__consumer_offsets", "__orders", "__transaction_state"}},
+	} {
+		w := newTestWatcher(t, b, `__.*`, tt.internal)
+		if _, err := w.refresh(); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(w.Topics(), tt.want) {
+			t.Errorf("internal %v: topics %v, want %v", tt.internal, w.Topics(), tt.want)
+		}
+	}
+}
+
+// testGroup is a consumer group whose sessions last until their context ends.
+type testGroup struct {
+	sessions chan []string
+}
+
+func (g *testGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	g.sessions <- topics
+	<-ctx.Done()
+	return nil
+}
+
+func (g *testGroup) Errors() <-chan error { return nil }
+
+func (g *testGroup) Close() error { return nil }
+
+func TestTopicWatcherRejoin(t *testing.T) {
+	b := newTopicBroker(t)
+	defer b.Close()
+	b.setTopics("orders.eu")
+	w := newTestWatcher(t, b, `orders\..*`, false)
+	if _, err := w.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.run(ctx, 10*time.Millisecond)
+
+	o := &consumerGOptions{topicPattern: `orders\..*`}
+	g := &testGroup{sessions: make(chan []string, 1)}
+	ended := make(chan struct{})
+	go func() {
+		o.consume(ctx, g, w.Topics(), w)
+		close(ended)
+	}()
+	if topics := <-g.sessions; !reflect.DeepEqual(topics, []string{"orders.eu"}) {
+		t.Fatalf("session topics %v", topics)
+	}
+
+	// refreshes which match the same topics keep the session
+	b.setTopics("orders.eu", "payments")
+	select {
+	case <-ended:
+		t.Fatal("the session ended although the matched topics are the same")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	b.setTopics("orders.eu", "orders.us", "payments")
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the session did not end after a matched topic was created")
+	}
+	if topics := w.Topics(); !reflect.DeepEqual(topics, []string{"orders.eu", "orders.us"}) {
+		t.Errorf("topics to rejoin with %v", topics)
+	}
+}
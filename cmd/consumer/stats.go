@@ -0,0 +1,139 @@
+package consumer
+
+import (
+	"context"
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/log"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// groupStats tracks the claimed partitions of consumerg for --stats-interval.
+type groupStats struct {
+	mu         sync.Mutex
+	partitions map[kafka.TopicPartition]*partitionStats
+}
+
+type partitionStats struct {
+	claim    sarama.ConsumerGroupClaim
+	consumed int64
+	// logged is consumed when the stats were logged last
+	logged int64
+	// offset is the next offset to consume, negative until it is known
+	offset int64
+}
+
+// statsRow is what is logged of a claimed partition.
+type statsRow struct {
+	tp       kafka.TopicPartition
+	consumed int64
+	// delta is the messages consumed since the last row
+	delta         int64
+	offset        int64
+	highWaterMark int64
+	lag           int64
+}
+
+func newGroupStats() *groupStats {
+	return &groupStats{partitions: map[kafka.TopicPartition]*partitionStats{}}
+}
+
+// claim starts tracking a claimed partition at offset, the consumed count
+// survives rebalances.
+func (s *groupStats) claim(c sarama.ConsumerGroupClaim, offset int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tp := kafka.TopicPartition{Topic: c.Topic(), Partition: c.Partition()}
+	p, ok := s.partitions[tp]
+	if !ok {
+		p = &partitionStats{}
+		s.partitions[tp] = p
+	}
+	p.claim = c
+	p.offset = offset
+}
+
+// release stops tracking the partitions of an ended session.
+func (s *groupStats) release(claims map[string][]int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for topic, partitions := range claims {
+		for _, partition := range partitions {
+			if p, ok := s.partitions[kafka.TopicPartition{Topic: topic, Partition: partition}]; ok {
+				p.claim = nil
+			}
+		}
+	}
+}
+
+func (s *groupStats) consumed(msg *sarama.ConsumerMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.partitions[kafka.TopicPartition{Topic: msg.Topic, Partition: msg.Partition}]; ok {
+		p.consumed++
+		p.offset = msg.Offset + 1
+	}
+}
+
+// snapshot returns the stats of every claimed partition, ordered by topic
+// and partition. The lag is the distance of the next offset to the high
+// water mark of the last fetch, unknown while the offset is.
+func (s *groupStats) snapshot() []statsRow {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows []statsRow
+	for tp, p := range s.partitions {
+		if p.claim == nil {
+			continue
+		}
+		row := statsRow{tp: tp, consumed: p.consumed, delta: p.consumed - p.logged, offset: p.offset, highWaterMark: -1, lag: -1}
+		p.logged = p.consumed
+		if p.offset >= 0 {
+			row.highWaterMark = p.claim.HighWaterMarkOffset()
+			// the high water mark is the one of the last fetch, it may be
+			// behind a message delivered from a later one
+			if row.lag = row.highWaterMark - p.offset; row.lag < 0 {
+				row.lag = 0
+			}
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].tp.Topic != rows[j].tp.Topic {
+			return rows[i].tp.Topic < rows[j].tp.Topic
+		}
+		return rows[i].tp.Partition < rows[j].tp.Partition
+	})
+	return rows
+}
+
+// log logs the consumed count, the messages consumed since the last log, the
+// next offset and the lag behind the high water mark of every claimed
+// partition.
+func (s *groupStats) log() {
+	for _, row := range s.snapshot() {
+		fields := []zap.Field{zap.String("topic", row.tp.Topic), zap.Int32("partition", row.tp.Partition),
+			zap.Int64("consumed", row.consumed), zap.Int64("sinceLast", row.delta)}
+		if row.offset >= 0 {
+			fields = append(fields, zap.Int64("offset", row.offset), zap.Int64("highWaterMark", row.highWaterMark), zap.Int64("lag", row.lag))
+		}
+		log.Info("Partition stats", fields...)
+	}
+}
+
+// run logs the stats every interval until ctx is done.
+func (s *groupStats) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.log()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
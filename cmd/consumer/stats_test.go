@@ -0,0 +1,96 @@
+package consumer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/kafka"
+)
+
+// testClaim is a claimed partition with a fixed high water mark.
+type testClaim struct {
+	topic         string
+	partition     int32
+	highWaterMark int64
+}
+
+func (c *testClaim) Topic() string                            { return c.topic }
+func (c *testClaim) Partition() int32                         { return c.partition }
+func (c *testClaim) InitialOffset() int64                     { return 0 }
+func (c *testClaim) HighWaterMarkOffset() int64               { return c.highWaterMark }
+func (c *testClaim) Messages() <-chan *sarama.ConsumerMessage { return nil }
+
+func TestGroupStatsLag(t *testing.T) {
+	tests := []struct {
+		name string
+		// start is the offset the claim starts at
+		start         int64
+		consumed      []int64
+		highWaterMark int64
+		want          statsRow
+	}{
+		{"nothing consumed", 40, nil, 50, statsRow{offset: 40, highWaterMark: 50, lag: 10}},
+		{"consumed", 40, []int64{40, 41, 42}, 50, statsRow{consumed: 3, delta: 3, offset: 43, highWaterMark: 50, lag: 7}},
+		{"caught up", 48, []int64{48, 49}, 50, statsRow{consumed: 2, delta: 2, offset: 50, highWaterMark: 50}},
+		// compaction and transaction markers leave gaps
+		{"gap", 0, []int64{3, 9}, 12, statsRow{consumed: 2, delta: 2, offset: 10, highWaterMark: 12, lag: 2}},
+		{"high water mark of an older fetch", 48, []int64{48, 49, 50}, 50, statsRow{consumed: 3, delta: 3, offset: 51, highWaterMark: 50}},
+		{"offset unknown", -1, nil, 50, statsRow{offset: -1, highWaterMark: -1, lag: -1}},
+		{"offset known once consumed", -1, []int64{7}, 50, statsRow{consumed: 1, delta: 1, offset: 8, highWaterMark: 50, lag: 42}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newGroupStats()
+			s.claim(&testClaim{topic: "t", highWaterMark: tt.highWaterMark}, tt.start)
+			for _, offset := range tt.consumed {
+				s.consumed(&sarama.ConsumerMessage{Topic: "t", Offset: offset})
+			}
+			tt.want.tp = kafka.TopicPartition{Topic: "t"}
+			if got := s.snapshot(); !reflect.DeepEqual(got, []statsRow{tt.want}) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupStatsInterval(t *testing.T) {
+	s := newGroupStats()
+	a := &testClaim{topic: "b", partition: 1, highWaterMark: 100}
+	b := &testClaim{topic: "a", partition: 0, highWaterMark: 100}
+	s.claim(a, 0)
+	s.claim(b, 0)
+	consume := func(topic string, partition int32, n int) {
+		for i := 0; i < n; i++ {
+			s.consumed(&sarama.ConsumerMessage{Topic: topic, Partition: partition})
+		}
+	}
+	deltas := func() (got [][2]int64) {
+		for _, row := range s.snapshot() {
+			got = append(got, [2]int64{row.consumed, row.delta})
+		}
+		return got
+	}
+
+	consume("b", 1, 5)
+	consume("a", 0, 2)
+	// ordered by topic and partition
+	if got, want := deltas(), [][2]int64{{2, 2}, {5, 5}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("first interval %v, want %v", got, want)
+	}
+	consume("b", 1, 3)
+	if got, want := deltas(), [][2]int64{{2, 0}, {8, 3}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("second interval %v, want %v", got, want)
+	}
+
+	// a revoked partition is not logged, its count survives the rebalance
+	s.release(map[string][]int32{"b": {1}})
+	if got, want := deltas(), [][2]int64{{2, 0}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after the revoke %v, want %v", got, want)
+	}
+	s.claim(a, 8)
+	consume("b", 1, 1)
+	if got, want := deltas(), [][2]int64{{2, 0}, {9, 1}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after the claim %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+package contexts
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thimico/kafka-cli/config"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/log"
+	"github.com/thimico/kafka-cli/utils"
+	"go.uber.org/zap"
+	"os"
+)
+
+var contextExample = `
+# List all contexts in the config file, the current one is marked with *
+    ./kafka-cli context list
+
+# Show the current context
+    ./kafka-cli context current
+
+# Switch to the prod context
+    ./kafka-cli context use prod
+
+# Use a context for a single command only
+    ./kafka-cli topic -l --context=staging
+    KAFKA_CLI_CONTEXT=staging ./kafka-cli topic -l
+`
+
+type contextOptions struct {
+	client *kafka.ClientOptions
+}
+
+func newContextOptions(clientOptions *kafka.ClientOptions) *contextOptions {
+	return &contextOptions{client: clientOptions}
+}
+
+func (o *contextOptions) list(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(o.client.ConfigFile)
+	if err != nil {
+		return err
+	}
+	utils.PrintContexts(cfg)
+	return nil
+}
+
+func (o *contextOptions) current(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(o.client.ConfigFile)
+	if err != nil {
+		return err
+	}
+	if cfg.CurrentContext == "" {
+		return utils.NotFoundError("current context is not set")
+	}
+	utils.PrintCurrentContext(cfg.CurrentContext)
+	return nil
+}
+
+func (o *contextOptions) use(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(o.client.ConfigFile)
+	if err != nil {
+		return err
+	}
+	name := args[0]
+	if _, ok := cfg.Contexts[name]; !ok {
+		return utils.NotFoundError("context %s not found in %s", name, cfg.Path())
+	}
+	cfg.CurrentContext = name
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	log.Info("Switched context", zap.String("context", name))
+	return nil
+}
+
+func NewCmdContext(clientOptions *kafka.ClientOptions) *cobra.Command {
+	o := newContextOptions(clientOptions)
+	cmd := &cobra.Command{
+		Use:     "context",
+		Short:   "Manage the cluster contexts of the config file",
+		Long:    "Manage the cluster contexts of the config file, a context holds the brokers, protocol version and authentication of a cluster",
+		Example: contextExample,
+		Run:     func(cmd *cobra.Command, args []string) { cmd.Help() },
+		// the context commands work on the config file itself, so they should
+		// not resolve a connection from it, only the output format applies
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			output := cmd.Flag("output")
+			if v, ok := os.LookupEnv(config.EnvName(output.Name)); ok && !output.Changed {
+				return utils.SetOutputFormat(v)
+			}
+			return utils.SetOutputFormat(output.Value.String())
+		},
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List all contexts",
+		Args:  cobra.NoArgs,
+		RunE:  o.list,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "current",
+		Short: "Show the current context",
+		Args:  cobra.NoArgs,
+		RunE:  o.current,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "use NAME",
+		Short: "Set the current context",
+		Args:  cobra.ExactArgs(1),
+		RunE:  o.use,
+	})
+	return cmd
+}
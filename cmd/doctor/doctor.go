@@ -0,0 +1,323 @@
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/Shopify/sarama"
+	"github.com/spf13/cobra"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/utils"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+var doctorExample = `
+# Check the connectivity to every bootstrap and advertised broker
+    ./kafka-cli doctor -b kafka-1:9093 --tls-ca-file=ca.pem --sasl-mechanism=SCRAM-SHA-512 --sasl-username=alice --sasl-password=secret
+    result:
+        TARGET              CHECK        RESULT  TIME      DETAIL
+        kafka-1:9093        dns          PASS    1ms       10.0.0.11
+        kafka-1:9093        tcp          PASS    2ms       10.0.0.12:51234 -> 10.0.0.11:9093
+        kafka-1:9093        tls          PASS    9ms       TLS1.3, CN=kafka-1, expires 2027-01-01
+        ...
+`
+
+type doctorOptions struct {
+	client *kafka.ClientOptions
+
+	timeout time.Duration
+}
+
+func newDoctorOptions(clientOptions *kafka.ClientOptions) *doctorOptions {
+	return &doctorOptions{client: clientOptions}
+}
+
+// doctor runs the checks of one invocation and collects their results.
+type doctor struct {
+	ctx     context.Context
+	config  *sarama.Config
+	timeout time.Duration
+	results []utils.CheckResult
+}
+
+func (o *doctorOptions) run(cmd *cobra.Command, args []string) error {
+	ctx, cancel := o.client.NewContext(cmd.Context())
+	defer cancel()
+	// the version detection is one of the checks, so it must not fail the config
+	clientOptions := *o.client
+	if clientOptions.KafkaVersion == kafka.VersionAuto {
+		clientOptions.KafkaVersion = ""
+	}
+	config, err := clientOptions.NewConfig()
+	if err != nil {
+		return err
+	}
+	config.Net.DialTimeout = o.timeout
+	config.Net.ReadTimeout = o.timeout
+	config.Net.WriteTimeout = o.timeout
+
+	d := &doctor{ctx: ctx, config: config, timeout: o.timeout}
+	advertised := map[string]int32{}
+	for _, addr := range o.client.Brokers() {
+		brokers := d.checkBootstrap(addr)
+		for _, b := range brokers {
+			advertised[b.Addr()] = b.ID()
+		}
+	}
+	if len(advertised) == 0 {
+		d.add(utils.CheckResult{Target: "cluster", Check: "metadata", Detail: "no broker returned metadata",
+			Hint: "fix the failed bootstrap checks above first"})
+	}
+	for _, addr := range sortedAddrs(advertised) {
+		d.checkAdvertised(addr, advertised[addr])
+	}
+
+	utils.PrintCheckResults(d.results)
+	if ctx.Err() != nil {
+		return kafka.ContextError(ctx)
+	}
+	failed := 0
+	kind := utils.KindConnectivity
+	for _, r := range d.results {
+		if !r.OK {
+			failed++
+			if r.Check == "sasl" {
+				kind = utils.KindAuth
+			}
+		}
+	}
+	if failed != 0 {
+		return utils.NewError(kind, fmt.Errorf("%d of %d checks failed", failed, len(d.results)))
+	}
+	return nil
+}
+
+// checkBootstrap checks a bootstrap server and returns the brokers it advertises.
+func (d *doctor) checkBootstrap(addr string) []*sarama.Broker {
+	if !d.checkConnection(addr, addr, "") {
+		return nil
+	}
+	return d.checkMetadata(addr, addr)
+}
+
+// checkAdvertised checks a broker address learned from metadata, failures there
+// while the bootstrap checks pass almost always mean a wrong advertised.listeners.
+func (d *doctor) checkAdvertised(addr string, id int32) {
+	target := fmt.Sprintf("#%d %s", id, addr)
+	if d.checkConnection(addr, target, fmt.Sprintf("broker %d advertises %s in advertised.listeners, which", id, addr)) {
+		d.checkMetadata(addr, target)
+	}
+}
+
+// checkMetadata fetches the metadata from a broker and returns the brokers it
+// advertises.
+func (d *doctor) checkMetadata(addr, target string) []*sarama.Broker {
+	var brokers []*sarama.Broker
+	d.check(target, "metadata", func() (string, error) {
+		res, err := d.fetchMetadata(addr)
+		if err != nil {
+			return "", err
+		}
+		brokers = res.Brokers
+		var addrs []string
+		for _, b := range res.Brokers {
+			addrs = append(addrs, fmt.Sprintf("%d=%s", b.ID(), b.Addr()))
+		}
+		return fmt.Sprintf("%d topics, controller %d, advertised %s", len(res.Topics), res.ControllerID, strings.Join(addrs, " ")), nil
+	}, func(err error) string {
+		return "the broker accepted the connection but could not serve metadata, check the broker logs and ACLs"
+	})
+	return brokers
+}
+
+// checkConnection runs the dns, tcp, tls, api versions and sasl checks, it stops
+// at the first failure since every later check depends on the former.
+func (d *doctor) checkConnection(addr, target, advertised string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		d.add(utils.CheckResult{Target: target, Check: "address", Detail: err.Error(), Hint: "brokers should be given as host:port"})
+		return false
+	}
+	hint := func(h string) func(error) string {
+		return func(error) string {
+			if advertised != "" {
+				return advertised + " " + h + ", fix advertised.listeners on the broker or the name resolution of this host"
+			}
+			return h
+		}
+	}
+
+	ok := d.check(target, "dns", func() (string, error) {
+		ips, err := net.LookupHost(host)
+		return strings.Join(ips, ","), err
+	}, hint("does not resolve from this host"))
+	ok = ok && d.check(target, "tcp", func() (string, error) {
+		conn, err := net.DialTimeout("tcp", addr, d.timeout)
+		if err != nil {
+			return "", err
+		}
+		defer conn.Close()
+		return fmt.Sprintf("%s -> %s", conn.LocalAddr(), conn.RemoteAddr()), nil
+	}, hint("is not reachable over tcp, check the port, firewalls and the listeners of the broker"))
+	if ok && d.config.Net.TLS.Enable {
+		ok = d.check(target, "tls", func() (string, error) {
+			return d.tlsHandshake(addr, host)
+		}, tlsHint)
+	}
+	ok = ok && d.check(target, "api-versions", func() (string, error) {
+		config := *d.config
+		config.Net.SASL.Enable = false
+		version, apiVersions, err := kafka.DetectVersion([]string{addr}, &config)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d apis, kafka >= %s", len(apiVersions), version), nil
+	}, func(err error) string {
+		if !d.config.Net.TLS.Enable {
+			return "the listener may expect TLS, try --tls, or it is not a kafka listener"
+		}
+		return "the listener answered but not with the kafka protocol, check the port"
+	})
+	if ok && d.config.Net.SASL.Enable {
+		ok = d.check(target, "sasl", func() (string, error) {
+			broker := sarama.NewBroker(addr)
+			if err := broker.Open(d.config); err != nil {
+				return "", err
+			}
+			defer broker.Close()
+			if _, err := broker.ApiVersions(&sarama.ApiVersionsRequest{}); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s as %s", d.config.Net.SASL.Mechanism, d.config.Net.SASL.User), nil
+		}, func(err error) string {
+			return "check --sasl-mechanism, --sasl-username and --sasl-password, and that the listener is SASL_PLAINTEXT or SASL_SSL with this mechanism enabled"
+		})
+	}
+	return ok
+}
+
+func (d *doctor) tlsHandshake(addr, host string) (string, error) {
+	tlsConfig := &tls.Config{}
+	if d.config.Net.TLS.Config != nil {
+		tlsConfig = d.config.Net.TLS.Config.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: d.timeout}, "tcp", addr, tlsConfig)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	state := conn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return tlsVersionName(state.Version), nil
+	}
+	leaf := state.PeerCertificates[0]
+	detail := fmt.Sprintf("%s, CN=%s, issuer CN=%s, expires %s", tlsVersionName(state.Version), leaf.Subject.CommonName,
+		leaf.Issuer.CommonName, leaf.NotAfter.Format("2006-01-02"))
+	if days := int(time.Until(leaf.NotAfter).Hours() / 24); days < 30 {
+		detail += fmt.Sprintf(" (in %d days)", days)
+	}
+	return detail, nil
+}
+
+func tlsHint(err error) string {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return "the broker certificate is not signed by a trusted CA, pass the CA with --tls-ca-file"
+	case errors.As(err, &hostname):
+		return "the broker certificate does not cover this host name, check its SANs or set --tls-server-name"
+	case errors.As(err, &invalid):
+		return "the broker certificate is not valid, check its validity period and key usage"
+	case strings.Contains(err.Error(), "first record does not look like a TLS handshake"):
+		return "the listener is not using TLS, remove the --tls flags or use the TLS port"
+	}
+	return "check the TLS settings of the listener and the client certificate"
+}
+
+func tlsVersionName(v uint16) string {
+	switch v {
+	case tls.VersionTLS10:
+		return "TLS1.0"
+	case tls.VersionTLS11:
+		return "TLS1.1"
+	case tls.VersionTLS12:
+		return "TLS1.2"
+	case tls.VersionTLS13:
+		return "TLS1.3"
+	}
+	return fmt.Sprintf("TLS(0x%04x)", v)
+}
+
+func (d *doctor) fetchMetadata(addr string) (*sarama.MetadataResponse, error) {
+	broker := sarama.NewBroker(addr)
+	if err := broker.Open(d.config); err != nil {
+		return nil, err
+	}
+	defer broker.Close()
+	req := &sarama.MetadataRequest{}
+	if d.config.Version.IsAtLeast(sarama.V0_10_1_0) {
+		req.Version = 2
+	} else if d.config.Version.IsAtLeast(sarama.V0_10_0_0) {
+		req.Version = 1
+	}
+	return broker.GetMetadata(req)
+}
+
+func (d *doctor) check(target, name string, f func() (string, error), hint func(error) string) bool {
+	// the remaining checks are skipped once the command is interrupted
+	if d.ctx.Err() != nil {
+		return false
+	}
+	start := time.Now()
+	var detail string
+	err := kafka.Wait(d.ctx, func() (err error) {
+		detail, err = f()
+		return err
+	})
+	r := utils.CheckResult{Target: target, Check: name, OK: err == nil, Duration: time.Since(start)}
+	if err != nil {
+		r.Detail = err.Error()
+		r.Hint = hint(err)
+	} else {
+		r.Detail = detail
+	}
+	d.add(r)
+	return r.OK
+}
+
+func (d *doctor) add(r utils.CheckResult) {
+	d.results = append(d.results, r)
+}
+
+func sortedAddrs(m map[string]int32) []string {
+	var addrs []string
+	for a := range m {
+		addrs = append(addrs, a)
+	}
+	// sort by broker id, so the output follows the cluster layout
+	sort.Slice(addrs, func(i, j int) bool { return m[addrs[i]] < m[addrs[j]] })
+	return addrs
+}
+
+func NewCmdDoctor(clientOptions *kafka.ClientOptions) *cobra.Command {
+	o := newDoctorOptions(clientOptions)
+	cmd := &cobra.Command{
+		Use:     "doctor",
+		Short:   "Diagnose the connectivity to the cluster",
+		Long:    "Diagnose the connectivity to the cluster, checks name resolution, tcp, tls, api versions, sasl and metadata of every bootstrap broker and then of every advertised broker",
+		Example: doctorExample,
+		RunE:    o.run,
+	}
+	cmd.Flags().DurationVar(&o.timeout, "check-timeout", 5*time.Second, "The timeout of every single network check")
+	return cmd
+}
@@ -0,0 +1,170 @@
+package doctor
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/utils"
+)
+
+// unresolvable is an advertised host which never resolves, .invalid is
+// reserved for that.
+const unresolvable = "kafka-1.invalid:9092"
+
+func newTestDoctor() *doctor {
+	config := sarama.NewConfig()
+	config.Version = sarama.V1_0_0_0
+	return &doctor{ctx: context.Background(), config: config, timeout: 2 * time.Second}
+}
+
+// newTestBroker starts a broker 1 which advertises itself at advertised, its
+// own address when empty.
+func newTestBroker(t *testing.T, advertised string) *sarama.MockBroker {
+	b := sarama.NewMockBroker(t, 1)
+	if advertised == "" {
+		advertised = b.Addr()
+	}
+	b.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": testApiVersions,
+		"MetadataRequest":    sarama.NewMockMetadataResponse(t).SetBroker(advertised, 1).SetController(1).SetLeader("orders", 0, 1),
+	})
+	return b
+}
+
+var testApiVersions = sarama.NewMockWrapper(&sarama.ApiVersionsResponse{ApiVersions: []*sarama.ApiVersionsResponseBlock{
+	{ApiKey: 0, MinVersion: 0, MaxVersion: 5},
+	{ApiKey: 3, MinVersion: 0, MaxVersion: 5},
+}})
+
+// row is the part of a check result which does not depend on timing.
+type row struct {
+	target, check string
+	ok            bool
+	hint          string
+}
+
+func assertResults(t *testing.T, got []utils.CheckResult, want []row) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d results %+v, want %d", len(got), got, len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Target != w.target || g.Check != w.check || g.OK != w.ok {
+			t.Errorf("result %d = %s %s ok=%v (%s), want %s %s ok=%v", i, g.Target, g.Check, g.OK, g.Detail, w.target, w.check, w.ok)
+		}
+		if w.hint == "" && g.Hint != "" {
+			t.Errorf("result %d has hint %q, want none", i, g.Hint)
+		}
+		if !strings.Contains(g.Hint, w.hint) {
+			t.Errorf("result %d hint = %q, want it to contain %q", i, g.Hint, w.hint)
+		}
+	}
+}
+
+func TestDoctorPass(t *testing.T) {
+	b := newTestBroker(t, "")
+	defer b.Close()
+
+	d := newTestDoctor()
+	brokers := d.checkBootstrap(b.Addr())
+	if len(brokers) != 1 || brokers[0].Addr() != b.Addr() {
+		t.Fatalf("advertised brokers = %v, want %s", brokers, b.Addr())
+	}
+	d.checkAdvertised(brokers[0].Addr(), brokers[0].ID())
+	advertised := "#1 " + b.Addr()
+	assertResults(t, d.results, []row{
+		{b.Addr(), "dns", true, ""},
+		{b.Addr(), "tcp", true, ""},
+		{b.Addr(), "api-versions", true, ""},
+		{b.Addr(), "metadata", true, ""},
+		{advertised, "dns", true, ""},
+		{advertised, "tcp", true, ""},
+		{advertised, "api-versions", true, ""},
+		{advertised, "metadata", true, ""},
+	})
+	if detail := d.results[3].Detail; !strings.Contains(detail, "1 topics") || !strings.Contains(detail, "1="+b.Addr()) {
+		t.Errorf("metadata detail = %q", detail)
+	}
+}
+
+func TestDoctorTCPFailure(t *testing.T) {
+	// a port which was just released refuses connections
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	d := newTestDoctor()
+	if brokers := d.checkBootstrap(addr); brokers != nil {
+		t.Fatalf("advertised brokers = %v, want none", brokers)
+	}
+	assertResults(t, d.results, []row{
+		{addr, "dns", true, ""},
+		{addr, "tcp", false, "is not reachable over tcp"},
+	})
+}
+
+func TestDoctorUnresolvableAdvertisedListener(t *testing.T) {
+	b := newTestBroker(t, unresolvable)
+	defer b.Close()
+
+	d := newTestDoctor()
+	brokers := d.checkBootstrap(b.Addr())
+	if len(brokers) != 1 || brokers[0].Addr() != unresolvable {
+		t.Fatalf("advertised brokers = %v, want %s", brokers, unresolvable)
+	}
+	d.checkAdvertised(brokers[0].Addr(), brokers[0].ID())
+	assertResults(t, d.results, []row{
+		{b.Addr(), "dns", true, ""},
+		{b.Addr(), "tcp", true, ""},
+		{b.Addr(), "api-versions", true, ""},
+		{b.Addr(), "metadata", true, ""},
+		{"#1 " + unresolvable, "dns", false, "broker 1 advertises " + unresolvable + " in advertised.listeners, which does not resolve from this host, fix advertised.listeners"},
+	})
+}
+
+func TestDoctorAdvertisedMetadataFailure(t *testing.T) {
+	b1 := sarama.NewMockBroker(t, 1)
+	defer b1.Close()
+	b2 := sarama.NewMockBroker(t, 2)
+	defer b2.Close()
+	b1.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": testApiVersions,
+		"MetadataRequest":    sarama.NewMockMetadataResponse(t).SetBroker(b1.Addr(), 1).SetBroker(b2.Addr(), 2).SetController(1).SetLeader("orders", 0, 1),
+	})
+	// broker 2 connects but answers metadata with a body which does not
+	// decode, like a broker failing in the middle of the request
+	b2.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": testApiVersions,
+		"MetadataRequest":    testApiVersions,
+	})
+
+	d := newTestDoctor()
+	if brokers := d.checkBootstrap(b1.Addr()); len(brokers) != 2 {
+		t.Fatalf("advertised brokers = %v, want 2", brokers)
+	}
+	d.checkAdvertised(b1.Addr(), 1)
+	d.checkAdvertised(b2.Addr(), 2)
+	first, second := "#1 "+b1.Addr(), "#2 "+b2.Addr()
+	assertResults(t, d.results, []row{
+		{b1.Addr(), "dns", true, ""},
+		{b1.Addr(), "tcp", true, ""},
+		{b1.Addr(), "api-versions", true, ""},
+		{b1.Addr(), "metadata", true, ""},
+		{first, "dns", true, ""},
+		{first, "tcp", true, ""},
+		{first, "api-versions", true, ""},
+		{first, "metadata", true, ""},
+		{second, "dns", true, ""},
+		{second, "tcp", true, ""},
+		{second, "api-versions", true, ""},
+		{second, "metadata", false, "could not serve metadata"},
+	})
+}
@@ -0,0 +1,228 @@
+package dump
+
+import (
+	"github.com/Shopify/sarama"
+	"github.com/spf13/cobra"
+	"github.com/thimico/kafka-cli/dump"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/log"
+	"github.com/thimico/kafka-cli/utils"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// idleCheckInterval is how often partitions without records are checked for
+// their end.
+const idleCheckInterval = time.Second
+
+var dumpExample = `
+# Dump every record of a topic, as it is right now, to a file
+    ./kafka-cli dump --topic=orders --out=orders.ndjson
+
+# Dump partitions 0 and 1 in the compact binary format
+    ./kafka-cli dump --topic=orders --partitions=0,1 --format=binary --out=orders.dump
+
+# Dump to stdout and compress
+    ./kafka-cli dump --topic=orders | gzip > orders.ndjson.gz
+`
+
+type dumpOptions struct {
+	client *kafka.ClientOptions
+
+	topic      string
+	partitions string
+	out        string
+	format     string
+}
+
+func newDumpOptions(clientOptions *kafka.ClientOptions) *dumpOptions {
+	return &dumpOptions{client: clientOptions}
+}
+
+func (o *dumpOptions) run(cmd *cobra.Command, args []string) (err error) {
+	if o.topic == "" {
+		return utils.UsageError("empty topic")
+	}
+	partitions, err := kafka.ParsePartitions(o.partitions)
+	if err != nil {
+		return utils.UsageError("%s", err)
+	}
+	if err := dump.CheckFormat(o.format); err != nil {
+		return utils.UsageError("--format: %s", err)
+	}
+	ctx, cancel := o.client.NewContext(cmd.Context())
+	defer cancel()
+	config, err := o.client.NewConfig()
+	if err != nil {
+		return err
+	}
+	config.Consumer.Return.Errors = true
+	var client sarama.Client
+	err = kafka.WaitOpen(ctx, func() (err error) {
+		client, err = kafka.NewClient(o.client.Brokers(), config)
+		return err
+	}, func() { client.Close() })
+	if err != nil {
+		return err
+	}
+	defer utils.Close(client, &err)
+	c, err := kafka.NewConsumerFromClient(client)
+	if err != nil {
+		return err
+	}
+	defer utils.Close(c, &err)
+	tps, err := kafka.ResolvePartitions(c, []string{o.topic}, partitions)
+	if err != nil {
+		return err
+	}
+
+	// every partition is dumped from its oldest record up to the high water
+	// mark it has now
+	start := map[kafka.TopicPartition]int64{}
+	end := map[kafka.TopicPartition]int64{}
+	for _, tp := range tps {
+		oldest, err := client.GetOffset(tp.Topic, tp.Partition, sarama.OffsetOldest)
+		if err != nil {
+			return err
+		}
+		newest, err := client.GetOffset(tp.Topic, tp.Partition, sarama.OffsetNewest)
+		if err != nil {
+			return err
+		}
+		if oldest < newest {
+			start[tp] = oldest
+			end[tp] = newest
+		}
+	}
+
+	// the output is created once the topic is known, a failure before
+	// leaves an existing file alone
+	var out io.Writer = os.Stdout
+	if o.out != "-" {
+		var f *os.File
+		if f, err = os.Create(o.out); err != nil {
+			return err
+		}
+		defer utils.Close(f, &err)
+		out = f
+	}
+	w, err := dump.NewWriter(out, o.format)
+	if err != nil {
+		return err
+	}
+	var records int64
+	defer func() {
+		if flushErr := w.Flush(); err == nil {
+			err = flushErr
+		}
+		log.Info("Dump finished", zap.String("topic", o.topic), zap.Int("partitions", len(tps)), zap.Int64("records", records))
+	}()
+	if len(start) == 0 {
+		return nil
+	}
+	pcs, err := kafka.ConsumePartitions(c, start)
+	if err != nil {
+		return err
+	}
+	defer utils.Close(pcs, &err)
+	// quiet counts the idle checks in a row a partition got no record, next
+	// is the offset after its last record
+	quiet := map[kafka.TopicPartition]int{}
+	next := map[kafka.TopicPartition]int64{}
+	for tp, offset := range start {
+		next[tp] = offset
+	}
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+	for len(end) > 0 {
+		select {
+		case msg := <-pcs.Messages():
+			tp := kafka.TopicPartition{Topic: msg.Topic, Partition: msg.Partition}
+			hwm, ok := end[tp]
+			if !ok {
+				continue
+			}
+			quiet[tp] = 0
+			next[tp] = msg.Offset + 1
+			if msg.Offset >= hwm {
+				// the offsets up to the high water mark were transaction markers
+				delete(end, tp)
+				continue
+			}
+			if err := w.Write(record(msg)); err != nil {
+				return err
+			}
+			records++
+			if msg.Offset+1 >= hwm {
+				delete(end, tp)
+			}
+		case err := <-pcs.Errors():
+			if _, ok := end[kafka.TopicPartition{Topic: err.Topic, Partition: err.Partition}]; ok {
+				// the partition consumer may have stopped, the dump would never finish
+				return err
+			}
+			log.Info("partition consumer", zap.String("topic", err.Topic), zap.Int32("partition", err.Partition), zap.Error(err.Err))
+		case <-ticker.C:
+			// sarama does not tell how far a partition consumer fetched, once
+			// a partition got no record for two checks in a row the offsets
+			// from its last record up to the end are fetched, the partition
+			// is dumped when they hold only transaction markers
+			for tp, hwm := range end {
+				if pcs.Consumer(tp).HighWaterMarkOffset() < hwm {
+					continue
+				}
+				if quiet[tp]++; quiet[tp] < 2 {
+					continue
+				}
+				skipped, err := kafka.OffsetsSkipped(client, tp, next[tp], hwm)
+				if err != nil {
+					log.Warn("Check the end of a partition", zap.String("topic", tp.Topic), zap.Int32("partition", tp.Partition), zap.Error(err))
+					continue
+				}
+				if skipped {
+					log.Info("Partition reached its end", zap.String("topic", tp.Topic), zap.Int32("partition", tp.Partition))
+					delete(end, tp)
+				}
+			}
+		case <-ctx.Done():
+			// the records so far are written, but the dump is incomplete
+			return kafka.ContextError(ctx)
+		}
+	}
+	return nil
+}
+
+func record(msg *sarama.ConsumerMessage) *dump.Record {
+	r := &dump.Record{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Timestamp: msg.Timestamp,
+		Key:       msg.Key,
+		Value:     msg.Value,
+	}
+	for _, h := range msg.Headers {
+		r.Headers = append(r.Headers, dump.Header{Key: h.Key, Value: h.Value})
+	}
+	return r
+}
+
+func NewCmdDump(clientOptions *kafka.ClientOptions) *cobra.Command {
+	o := newDumpOptions(clientOptions)
+	cmd := &cobra.Command{
+		Use:     "dump",
+		Short:   "Dump the records of a topic to a file",
+		Long:    "Dump every record a topic holds when the command starts, with its partition, offset, timestamp, key, value and headers, to a file which restore replays",
+		Example: dumpExample,
+		Args:    cobra.NoArgs,
+		RunE:    o.run,
+	}
+	cmd.Flags().StringVar(&o.topic, "topic", "", "REQUIRED: The topic to dump")
+	cmd.Flags().StringVar(&o.partitions, "partitions", kafka.PartitionsAll, "The partitions to dump, all or a list like 0,3,5")
+	cmd.Flags().StringVar(&o.out, "out", "-", "The file to write, - for stdout")
+	cmd.Flags().StringVar(&o.format, "format", dump.FormatNDJSON, "The format of the file, one of "+strings.Join(dump.Formats, ", ")+", keys, values and headers are base64 in ndjson")
+	return cmd
+}
@@ -0,0 +1,163 @@
+package dump
+
+import (
+	"fmt"
+	"github.com/Shopify/sarama"
+	"github.com/spf13/cobra"
+	"github.com/thimico/kafka-cli/dump"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/log"
+	"github.com/thimico/kafka-cli/utils"
+	"go.uber.org/zap"
+	"io"
+	"os"
+)
+
+var restoreExample = `
+# Replay a dump into another topic, records are partitioned by their keys
+    ./kafka-cli restore --in=orders.ndjson --topic=orders-copy
+
+# Keep the partitions and timestamps of the dumped records
+    ./kafka-cli restore --in=orders.dump --topic=orders --preserve-partitions --preserve-timestamps -b staging:9092
+
+# Restore from stdin
+    gunzip -c orders.ndjson.gz | ./kafka-cli restore --topic=orders
+`
+
+// restoreBatch is the number of records sent at once.
+const restoreBatch = 500
+
+type restoreOptions struct {
+	client *kafka.ClientOptions
+
+	in                 string
+	topic              string
+	preservePartitions bool
+	preserveTimestamps bool
+}
+
+func newRestoreOptions(clientOptions *kafka.ClientOptions) *restoreOptions {
+	return &restoreOptions{client: clientOptions}
+}
+
+func (o *restoreOptions) run(cmd *cobra.Command, args []string) (err error) {
+	if o.topic == "" {
+		return utils.UsageError("empty topic")
+	}
+	var in io.Reader = os.Stdin
+	name := "stdin"
+	if o.in != "-" {
+		name = o.in
+		var f *os.File
+		if f, err = os.Open(o.in); err != nil {
+			return utils.UsageError("--in: %s", err)
+		}
+		defer utils.Close(f, &err)
+		in = f
+	}
+	r, err := dump.NewReader(in)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := o.client.NewContext(cmd.Context())
+	defer cancel()
+	config, err := o.client.NewConfig()
+	if err != nil {
+		return err
+	}
+	if o.preserveTimestamps && !config.Version.IsAtLeast(sarama.V0_10_0_0) {
+		return utils.UsageError("--preserve-timestamps needs --kafka-version 0.10.0 or later")
+	}
+	if o.preservePartitions {
+		config.Producer.Partitioner = sarama.NewManualPartitioner
+	}
+	config.Producer.Return.Successes = true
+
+	var client sarama.Client
+	err = kafka.WaitOpen(ctx, func() (err error) {
+		client, err = kafka.NewClient(o.client.Brokers(), config)
+		return err
+	}, func() { client.Close() })
+	if err != nil {
+		return err
+	}
+	defer utils.Close(client, &err)
+	partitions, err := client.Partitions(o.topic)
+	if err != nil {
+		return fmt.Errorf("topic %s: %w", o.topic, err)
+	}
+	producer, err := kafka.NewProducerFromClient(client)
+	if err != nil {
+		return err
+	}
+	defer utils.Close(producer, &err)
+
+	var records int64
+	defer func() {
+		log.Info("Restore finished", zap.String("topic", o.topic), zap.Int64("records", records))
+	}()
+	batch := make([]*sarama.ProducerMessage, 0, restoreBatch)
+	for eof := false; !eof; {
+		rec, err := r.Read()
+		if err == io.EOF {
+			eof = true
+		} else if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		} else {
+			if o.preservePartitions && int(rec.Partition) >= len(partitions) {
+				return fmt.Errorf("topic %s has %d partitions, the dump has records of partition %d", o.topic, len(partitions), rec.Partition)
+			}
+			batch = append(batch, o.message(rec))
+		}
+		if len(batch) == restoreBatch || (eof && len(batch) > 0) {
+			if err := kafka.Wait(ctx, func() error { return producer.SendMessages(batch) }); err != nil {
+				if errs, ok := err.(sarama.ProducerErrors); ok {
+					records += int64(len(batch) - len(errs))
+					err = fmt.Errorf("%d of %d records of a batch failed, the first: %w", len(errs), len(batch), errs[0].Err)
+				}
+				return err
+			}
+			records += int64(len(batch))
+			batch = batch[:0]
+		}
+	}
+	return nil
+}
+
+// message returns the producer message of a dumped record.
+func (o *restoreOptions) message(rec *dump.Record) *sarama.ProducerMessage {
+	msg := &sarama.ProducerMessage{Topic: o.topic}
+	if rec.Key != nil {
+		msg.Key = sarama.ByteEncoder(rec.Key)
+	}
+	if rec.Value != nil {
+		msg.Value = sarama.ByteEncoder(rec.Value)
+	}
+	for _, h := range rec.Headers {
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+	if o.preservePartitions {
+		msg.Partition = rec.Partition
+	}
+	if o.preserveTimestamps {
+		msg.Timestamp = rec.Timestamp
+	}
+	return msg
+}
+
+func NewCmdRestore(clientOptions *kafka.ClientOptions) *cobra.Command {
+	o := newRestoreOptions(clientOptions)
+	cmd := &cobra.Command{
+		Use:     "restore",
+		Short:   "Replay a dump into a topic",
+		Long:    "Replay the records of a file written by dump into a topic, the format of the file is detected",
+		Example: restoreExample,
+		Args:    cobra.NoArgs,
+		RunE:    o.run,
+	}
+	cmd.Flags().StringVar(&o.in, "in", "-", "The dump to read, - for stdin")
+	cmd.Flags().StringVar(&o.topic, "topic", "", "REQUIRED: The topic to produce the records to")
+	cmd.Flags().BoolVar(&o.preservePartitions, "preserve-partitions", false, "Produce every record to the partition it was dumped from instead of partitioning by key")
+	cmd.Flags().BoolVar(&o.preserveTimestamps, "preserve-timestamps", false, "Produce every record with its dumped timestamp instead of the current time")
+	return cmd
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
 	"github.com/thimico/kafka-cli/cmd/admin"
 	"github.com/thimico/kafka-cli/cmd/consumer"
+	"github.com/thimico/kafka-cli/cmd/contexts"
+	"github.com/thimico/kafka-cli/cmd/doctor"
+	"github.com/thimico/kafka-cli/cmd/dump"
+	"github.com/thimico/kafka-cli/cmd/perf"
 	"github.com/thimico/kafka-cli/cmd/producer"
 	"github.com/thimico/kafka-cli/cmd/topic"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/utils"
 	"github.com/spf13/cobra"
 	"math/rand"
 	"os"
@@ -13,9 +20,13 @@ import (
 
 func main() {
 	rand.Seed(time.Now().UnixNano())
+	ctx, stop := utils.SignalContext(context.Background())
 	command := NewKafkaCliCommand()
-	if err := command.Execute(); err != nil {
-		os.Exit(1)
+	err := command.ExecuteContext(ctx)
+	stop()
+	if err != nil {
+		utils.LogError(err)
+		os.Exit(utils.ExitCode(err))
 	}
 }
 
@@ -25,12 +36,35 @@ func NewKafkaCliCommand() *cobra.Command {
 		Short: "a command line tools for apache kafka",
 		Long:  "a command line tools for apache kafka, include topic,consumer,producer, admin's operations",
 		Run:   runHelp,
+		// errors are logged by main with their kind and exit code
+		SilenceErrors: true,
+		SilenceUsage:  true,
 	}
-	cmds.AddCommand(consumer.NewCmdConsumeGroup())
-	cmds.AddCommand(consumer.NewCmdConsumer())
-	cmds.AddCommand(topic.NewCmdTopic())
-	cmds.AddCommand(admin.NewCmdAdmin())
-	cmds.AddCommand(producer.NewCmdProducer())
+	cmds.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return utils.UsageError("%s, see %s --help", err, cmd.CommandPath())
+	})
+	clientOptions := kafka.NewClientOptions()
+	clientOptions.AddFlags(cmds.PersistentFlags())
+	output := utils.OutputTable
+	cmds.PersistentFlags().StringVarP(&output, "output", "o", output, "The output format, one of table, wide, json, yaml, ndjson or template=<go template>")
+	cmds.SetGlobalNormalizationFunc(kafka.NormalizeFlagName)
+	cmds.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := clientOptions.Complete(cmds.PersistentFlags()); err != nil {
+			return utils.NewError(utils.KindUsage, err)
+		}
+		return utils.SetOutputFormat(output)
+	}
+
+	cmds.AddCommand(consumer.NewCmdConsumeGroup(clientOptions))
+	cmds.AddCommand(consumer.NewCmdConsumer(clientOptions))
+	cmds.AddCommand(topic.NewCmdTopic(clientOptions))
+	cmds.AddCommand(admin.NewCmdAdmin(clientOptions))
+	cmds.AddCommand(producer.NewCmdProducer(clientOptions))
+	cmds.AddCommand(contexts.NewCmdContext(clientOptions))
+	cmds.AddCommand(doctor.NewCmdDoctor(clientOptions))
+	cmds.AddCommand(dump.NewCmdDump(clientOptions))
+	cmds.AddCommand(dump.NewCmdRestore(clientOptions))
+	cmds.AddCommand(perf.NewCmdPerf(clientOptions))
 	return cmds
 }
 
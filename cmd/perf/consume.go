@@ -0,0 +1,380 @@
+package perf
+
+import (
+	"context"
+	"github.com/Shopify/sarama"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/log"
+	"github.com/thimico/kafka-cli/perf"
+	"github.com/thimico/kafka-cli/utils"
+	"go.uber.org/zap"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var consumeExample = `
+# Consume a million records of every partition of a topic from the oldest offset
+    ./kafka-cli perf consume --topic=perf --messages=1e6
+
+# Consume with a new consumer group, the time of every rebalance is logged
+    ./kafka-cli perf consume --topic=perf --messages=1e6 --group=perf-$(date +%s)
+
+# Compare fetch settings against the same records
+    ./kafka-cli perf consume --topic=perf --messages=1e6 --fetch-min-bytes=1 --fetch-max-wait=100ms
+    ./kafka-cli perf consume --topic=perf --messages=1e6 --fetch-min-bytes=1048576 --fetch-max-wait=500ms --partition-fetch-bytes=4194304 --verbose
+`
+
+type consumeOptions struct {
+	client *kafka.ClientOptions
+	report reportOptions
+
+	topic               string
+	messages            countValue
+	group               string
+	partitions          string
+	offset              string
+	idleTimeout         time.Duration
+	fetchMinBytes       int32
+	fetchMaxBytes       int32
+	fetchMaxWait        time.Duration
+	partitionFetchBytes int32
+	bufferSize          int
+
+	meter    *perf.Meter
+	total    perf.Stats
+	start    time.Time
+	done     context.CancelFunc
+	consumed int64
+	// lastMessage is the time of the last message in unix nanoseconds, or of
+	// the last assignment, rebalancing is set while the group rebalances
+	lastMessage  int64
+	rebalancing  int32
+	firstOnce    sync.Once
+	firstMessage time.Duration
+
+	mu             sync.Mutex
+	joinStart      time.Time
+	rebalances     int
+	rebalanceTotal time.Duration
+}
+
+func newConsumeOptions(clientOptions *kafka.ClientOptions) *consumeOptions {
+	return &consumeOptions{client: clientOptions, messages: 1000000}
+}
+
+// applyFetchConfig applies the fetch flags, only when given, so they do not
+// override --client-property.
+func (o *consumeOptions) applyFetchConfig(flags *pflag.FlagSet, config *sarama.Config) error {
+	switch o.offset {
+	case "oldest":
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	case "newest":
+		config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	default:
+		return utils.UsageError("invalid --offset %q, should be oldest or newest", o.offset)
+	}
+	if flags.Changed("fetch-min-bytes") {
+		if o.fetchMinBytes <= 0 {
+			return utils.UsageError("--fetch-min-bytes should be positive")
+		}
+		config.Consumer.Fetch.Min = o.fetchMinBytes
+	}
+	if flags.Changed("fetch-max-bytes") {
+		if o.fetchMaxBytes < 0 {
+			return utils.UsageError("--fetch-max-bytes should not be negative")
+		}
+		config.Consumer.Fetch.Max = o.fetchMaxBytes
+	}
+	if flags.Changed("fetch-max-wait") {
+		if o.fetchMaxWait <= 0 {
+			return utils.UsageError("--fetch-max-wait should be positive")
+		}
+		config.Consumer.MaxWaitTime = o.fetchMaxWait
+	}
+	if flags.Changed("partition-fetch-bytes") {
+		if o.partitionFetchBytes <= 0 {
+			return utils.UsageError("--partition-fetch-bytes should be positive")
+		}
+		config.Consumer.Fetch.Default = o.partitionFetchBytes
+	}
+	if flags.Changed("buffer-size") {
+		if o.bufferSize < 0 {
+			return utils.UsageError("--buffer-size should not be negative")
+		}
+		config.ChannelBufferSize = o.bufferSize
+	}
+	if err := config.Validate(); err != nil {
+		return utils.UsageError("%s", err)
+	}
+	return nil
+}
+
+// logConsumerConfig logs the effective fetch settings, after the client
+// properties and the flags are applied.
+func logConsumerConfig(config *sarama.Config) {
+	c := config.Consumer
+	log.Info("Consumer config", zap.Int32("fetchMinBytes", c.Fetch.Min), zap.Int32("fetchMaxBytes", c.Fetch.Max),
+		zap.Duration("fetchMaxWait", c.MaxWaitTime), zap.Int32("partitionFetchBytes", c.Fetch.Default),
+		zap.Int("bufferSize", config.ChannelBufferSize), zap.String("version", config.Version.String()))
+}
+
+func (o *consumeOptions) run(cmd *cobra.Command, args []string) (err error) {
+	if o.topic == "" {
+		return utils.UsageError("empty topic")
+	}
+	if o.messages <= 0 {
+		return utils.UsageError("--messages should be positive")
+	}
+	if o.group != "" && cmd.Flags().Changed("partitions") {
+		return utils.UsageError("--group and --partitions are mutually exclusive, the group assigns the partitions")
+	}
+	partitions, err := kafka.ParsePartitions(o.partitions)
+	if err != nil {
+		return utils.UsageError("%s", err)
+	}
+	if o.idleTimeout <= 0 {
+		return utils.UsageError("--idle-timeout should be positive")
+	}
+	ctx, cancel := o.client.NewContext(cmd.Context())
+	defer cancel()
+	config, err := o.client.NewConfig()
+	if err != nil {
+		return err
+	}
+	if err := o.applyFetchConfig(cmd.Flags(), config); err != nil {
+		return err
+	}
+	if o.client.Verbose {
+		logConsumerConfig(config)
+	}
+
+	// the test ends once --messages are consumed, the context of the command
+	// only tells why it ended
+	testCtx, done := context.WithCancel(ctx)
+	defer done()
+	o.done = done
+	if o.group != "" {
+		err = o.consumeGroup(testCtx, config)
+	} else {
+		err = o.consumePartitions(testCtx, config, partitions)
+	}
+	if err != nil {
+		return err
+	}
+	return o.finish(ctx)
+}
+
+// begin starts measuring once the consumer is connected.
+func (o *consumeOptions) begin(ctx context.Context) (*reporter, error) {
+	o.meter = perf.NewMeter()
+	o.start = time.Now()
+	atomic.StoreInt64(&o.lastMessage, o.start.UnixNano())
+	r, err := o.report.startReporter(o.meter, false)
+	if err != nil {
+		return nil, err
+	}
+	go o.watchIdle(ctx)
+	return r, nil
+}
+
+// message counts a consumed message and ends the test with the last one,
+// the messages the other claims of a group consume meanwhile are not counted.
+func (o *consumeOptions) message(msg *sarama.ConsumerMessage) {
+	n := atomic.AddInt64(&o.consumed, 1)
+	if n > int64(o.messages) {
+		return
+	}
+	now := time.Now()
+	o.firstOnce.Do(func() {
+		o.firstMessage = now.Sub(o.start)
+	})
+	atomic.StoreInt64(&o.lastMessage, now.UnixNano())
+	o.meter.Record(len(msg.Key) + len(msg.Value))
+	if n == int64(o.messages) {
+		o.done()
+	}
+}
+
+// watchIdle ends the test once no message arrived for --idle-timeout, the
+// time the group rebalances does not count.
+func (o *consumeOptions) watchIdle(ctx context.Context) {
+	ticker := time.NewTicker(o.idleTimeout / 10)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		if atomic.LoadInt32(&o.rebalancing) == 1 {
+			atomic.StoreInt64(&o.lastMessage, time.Now().UnixNano())
+			continue
+		}
+		if time.Since(time.Unix(0, atomic.LoadInt64(&o.lastMessage))) >= o.idleTimeout {
+			log.Warn("No message for --idle-timeout, stopping", zap.Duration("idleTimeout", o.idleTimeout))
+			o.done()
+			return
+		}
+	}
+}
+
+func (o *consumeOptions) consumePartitions(ctx context.Context, config *sarama.Config, partitions []int32) (err error) {
+	config.Consumer.Return.Errors = true
+	var c sarama.Consumer
+	err = kafka.WaitOpen(ctx, func() (err error) {
+		c, err = kafka.NewConsumer(o.client.Brokers(), config)
+		return err
+	}, func() { c.Close() })
+	if err != nil {
+		return err
+	}
+	defer utils.Close(c, &err)
+	tps, err := kafka.ResolvePartitions(c, []string{o.topic}, partitions)
+	if err != nil {
+		return err
+	}
+	offsets := map[kafka.TopicPartition]int64{}
+	for _, tp := range tps {
+		offsets[tp] = config.Consumer.Offsets.Initial
+	}
+	r, err := o.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.stopReporter(r, &err)
+	pcs, err := kafka.ConsumePartitions(c, offsets)
+	if err != nil {
+		return err
+	}
+	defer utils.Close(pcs, &err)
+	for {
+		select {
+		case msg := <-pcs.Messages():
+			o.message(msg)
+		case err := <-pcs.Errors():
+			log.Warn("Partition consumer failed", zap.String("topic", err.Topic), zap.Int32("partition", err.Partition), zap.Error(err.Err))
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (o *consumeOptions) consumeGroup(ctx context.Context, config *sarama.Config) (err error) {
+	var c sarama.ConsumerGroup
+	err = kafka.WaitOpen(ctx, func() (err error) {
+		c, err = kafka.NewConsumerGroup(o.client.Brokers(), o.group, config)
+		return err
+	}, func() { c.Close() })
+	if err != nil {
+		return err
+	}
+	defer utils.Close(c, &err)
+	o.joinStart = time.Now()
+	atomic.StoreInt32(&o.rebalancing, 1)
+	r, err := o.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.stopReporter(r, &err)
+	for {
+		// Consume returns on every rebalance, it has to be called again to rejoin
+		if err := c.Consume(ctx, []string{o.topic}, o); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// Setup logs the claimed partitions and how long the rebalance took.
+func (o *consumeOptions) Setup(sess sarama.ConsumerGroupSession) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	rebalance := time.Since(o.joinStart)
+	o.rebalances++
+	o.rebalanceTotal += rebalance
+	atomic.StoreInt64(&o.lastMessage, time.Now().UnixNano())
+	atomic.StoreInt32(&o.rebalancing, 0)
+	log.Info("Partitions assigned", zap.String("memberId", sess.MemberID()), zap.Int32("generationId", sess.GenerationID()),
+		zap.Any("claims", sess.Claims()), zap.Duration("rebalance", rebalance))
+	return nil
+}
+
+// Cleanup starts timing the next rebalance.
+func (o *consumeOptions) Cleanup(sess sarama.ConsumerGroupSession) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.joinStart = time.Now()
+	atomic.StoreInt32(&o.rebalancing, 1)
+	return nil
+}
+
+func (o *consumeOptions) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		o.message(msg)
+		sess.MarkMessage(msg, "")
+	}
+	return nil
+}
+
+// stopReporter prints the totals once the consumers stopped.
+func (o *consumeOptions) stopReporter(r *reporter, err *error) {
+	total, stopErr := r.stop()
+	o.total = total
+	if *err == nil {
+		*err = stopErr
+	}
+}
+
+// finish logs the time to the first message, the rebalances and the fetch
+// throughput, which leaves out the time the group spent rebalancing.
+func (o *consumeOptions) finish(ctx context.Context) error {
+	total := o.total
+	fields := []zap.Field{zap.String("topic", o.topic), zap.Int64("consumed", total.Records)}
+	if total.Records > 0 {
+		fields = append(fields, zap.Duration("firstMessage", o.firstMessage))
+	}
+	fetch := total
+	if o.group != "" {
+		fields = append(fields, zap.String("group", o.group), zap.Int("rebalances", o.rebalances),
+			zap.Duration("rebalanceTime", o.rebalanceTotal))
+		fetch.Elapsed -= o.rebalanceTotal
+	}
+	fields = append(fields, zap.Float64("fetchRecordsPerSec", fetch.RecordsPerSec()), zap.Float64("fetchMBPerSec", fetch.MBPerSec()))
+	log.Info("Consume perf finished", fields...)
+	if ctx.Err() != nil {
+		log.Info("Perf test stopped early", zap.Error(kafka.ContextError(ctx)))
+	} else if total.Records < int64(o.messages) {
+		log.Warn("Fewer messages than --messages were consumed", zap.Int64("messages", int64(o.messages)), zap.Int64("consumed", total.Records))
+	}
+	return nil
+}
+
+func NewCmdPerfConsume(clientOptions *kafka.ClientOptions) *cobra.Command {
+	o := newConsumeOptions(clientOptions)
+	cmd := &cobra.Command{
+		Use:     "consume",
+		Short:   "Measure the consumer throughput",
+		Long:    "Consume --messages records of a topic, of every partition or with a consumer group, and report the records and MB per second at an interval and at the end, the time to the first message and, with a group, the time of every rebalance",
+		Example: consumeExample,
+		Args:    cobra.NoArgs,
+		RunE:    o.run,
+	}
+	o.report.addFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.topic, "topic", "", "REQUIRED: The topic to consume")
+	cmd.Flags().Var(&o.messages, "messages", "The number of messages to consume, e.g. 1e6")
+	cmd.Flags().StringVar(&o.group, "group", "", "Consume with this consumer group instead of reading the partitions directly")
+	cmd.Flags().StringVar(&o.partitions, "partitions", kafka.PartitionsAll, "The partitions to consume without a group, all or a list like 0,3,5")
+	cmd.Flags().StringVar(&o.offset, "offset", "oldest", "Where to start, oldest or newest, with a group only where it has no committed offset")
+	cmd.Flags().DurationVar(&o.idleTimeout, "idle-timeout", 10*time.Second, "Stop once no message arrived for this long, rebalances do not count")
+	cmd.Flags().Int32Var(&o.fetchMinBytes, "fetch-min-bytes", 1, "The least bytes a fetch waits for, like fetch.min.bytes")
+	cmd.Flags().Int32Var(&o.fetchMaxBytes, "fetch-max-bytes", 0, "The most bytes of a fetch response, like fetch.max.bytes, 0 for no limit")
+	cmd.Flags().DurationVar(&o.fetchMaxWait, "fetch-max-wait", 250*time.Millisecond, "The longest a fetch waits for --fetch-min-bytes, like fetch.max.wait.ms")
+	cmd.Flags().Int32Var(&o.partitionFetchBytes, "partition-fetch-bytes", 1024*1024, "The bytes fetched of every partition, like max.partition.fetch.bytes")
+	cmd.Flags().IntVar(&o.bufferSize, "buffer-size", 256, "The messages buffered of every partition")
+	return cmd
+}
@@ -0,0 +1,153 @@
+package perf
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/perf"
+	"github.com/thimico/kafka-cli/utils"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// countValue is an int64 flag which also takes the float notation of large
+// counts, e.g. 1e6.
+type countValue int64
+
+func (c *countValue) String() string {
+	return strconv.FormatInt(int64(*c), 10)
+}
+
+func (c *countValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f != float64(int64(f)) {
+		return fmt.Errorf("should be a whole number like 1000000 or 1e6")
+	}
+	*c = countValue(f)
+	return nil
+}
+
+func (c *countValue) Type() string {
+	return "count"
+}
+
+// reportOptions are the reporting flags of every perf test.
+type reportOptions struct {
+	interval time.Duration
+	csv      string
+}
+
+func (o *reportOptions) addFlags(flags *pflag.FlagSet) {
+	flags.DurationVar(&o.interval, "report-interval", 5*time.Second, "Print the throughput of the last window at this interval, 0 prints only the totals")
+	flags.StringVar(&o.csv, "csv", "", "Write the throughput of every window to this csv file, as a time series")
+}
+
+// reporter prints the windows of a meter at the report interval, and the
+// last window and the totals when it is stopped.
+type reporter struct {
+	meter   *perf.Meter
+	latency bool
+	// periodic is set when windows are printed at an interval
+	periodic bool
+	csv      *perf.CSVWriter
+	file     *os.File
+
+	done chan struct{}
+	wg   sync.WaitGroup
+	err  error
+}
+
+// startReporter starts reporting meter, latency adds the latency columns.
+func (o *reportOptions) startReporter(meter *perf.Meter, latency bool) (*reporter, error) {
+	r := &reporter{meter: meter, latency: latency, done: make(chan struct{})}
+	if o.csv != "" {
+		f, err := os.Create(o.csv)
+		if err != nil {
+			return nil, utils.UsageError("--csv: %s", err)
+		}
+		r.file = f
+		if r.csv, err = perf.NewCSVWriter(f, time.Now(), latency); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if o.interval <= 0 {
+		return r, nil
+	}
+	r.periodic = true
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(o.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.window(r.meter.Window())
+			case <-r.done:
+				return
+			}
+		}
+	}()
+	return r, nil
+}
+
+func (r *reporter) window(s perf.Stats) {
+	if r.periodic {
+		utils.PrintPerfReport(r.report("interval", s))
+	}
+	if r.csv != nil && r.err == nil {
+		r.err = r.csv.Write(s)
+	}
+}
+
+// stop reports the last window, when it has records, and the totals. Without
+// an interval only the totals are printed, --csv still gets the window.
+func (r *reporter) stop() (total perf.Stats, err error) {
+	close(r.done)
+	r.wg.Wait()
+	if last := r.meter.Window(); last.Records > 0 || last.Errors > 0 {
+		r.window(last)
+	}
+	total = r.meter.Total()
+	utils.PrintPerfReport(r.report("total", total))
+	if r.file != nil {
+		if err := r.file.Close(); r.err == nil {
+			r.err = err
+		}
+	}
+	if r.err != nil {
+		return total, fmt.Errorf("write --csv: %s", r.err)
+	}
+	return total, nil
+}
+
+func (r *reporter) report(window string, s perf.Stats) utils.PerfReport {
+	report := utils.PerfReport{Window: window, Elapsed: s.Elapsed, Records: s.Records,
+		RecordsPerSec: s.RecordsPerSec(), MBPerSec: s.MBPerSec(), Errors: s.Errors}
+	if r.latency {
+		report.Latency = &utils.LatencyReport{
+			P50:  perf.Millis(s.Latency.Quantile(0.5)),
+			P95:  perf.Millis(s.Latency.Quantile(0.95)),
+			P99:  perf.Millis(s.Latency.Quantile(0.99)),
+			Max:  perf.Millis(s.Latency.Max()),
+			Mean: perf.Millis(s.Latency.Mean()),
+		}
+	}
+	return report
+}
+
+func NewCmdPerf(clientOptions *kafka.ClientOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "perf",
+		Short: "Measure the producer and consumer throughput of a cluster",
+		Long:  "Measure the producer and consumer throughput of a cluster, like kafka-producer-perf-test and kafka-consumer-perf-test without the java distribution",
+		Run:   func(cmd *cobra.Command, args []string) { cmd.Help() },
+	}
+	cmd.AddCommand(NewCmdPerfProduce(clientOptions))
+	cmd.AddCommand(NewCmdPerfConsume(clientOptions))
+	return cmd
+}
@@ -0,0 +1,166 @@
+package perf
+
+import (
+	"fmt"
+	"github.com/Shopify/sarama"
+	"github.com/spf13/cobra"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/log"
+	"github.com/thimico/kafka-cli/perf"
+	"github.com/thimico/kafka-cli/utils"
+	"go.uber.org/zap"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var produceExample = `
+# Produce a million random 1KB records at 5000 records per second
+    ./kafka-cli perf produce --topic=perf --record-size=1024 --num-records=1e6 --throughput=5000
+
+# Produce as fast as possible with the settings of a service, and keep the time series
+    ./kafka-cli perf produce --topic=perf --record-size=512 --num-records=1e7 --client-property=acks=1 --client-property=compression.type=lz4 --client-property=linger.ms=5 --csv=produce.csv
+
+# Produce records sampled from the lines of a file, the totals as json
+    ./kafka-cli perf produce --topic=perf --payload-file=orders.ndjson --num-records=1e5 --report-interval=0 -o json
+`
+
+type produceOptions struct {
+	client *kafka.ClientOptions
+	report reportOptions
+
+	topic            string
+	numRecords       countValue
+	recordSize       int
+	throughput       float64
+	payload          string
+	payloadFile      string
+	payloadDelimiter string
+}
+
+func newProduceOptions(clientOptions *kafka.ClientOptions) *produceOptions {
+	return &produceOptions{client: clientOptions, numRecords: 1000000}
+}
+
+func (o *produceOptions) payloads(cmd *cobra.Command) (perf.Payloads, error) {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	if o.payloadFile == "" {
+		payloads, err := perf.NewPayloads(o.payload, o.recordSize, rnd)
+		if err != nil {
+			return nil, utils.UsageError("%s", err)
+		}
+		return payloads, nil
+	}
+	if cmd.Flags().Changed("record-size") || cmd.Flags().Changed("payload") {
+		return nil, utils.UsageError("--payload-file can not be used with --record-size or --payload, the records are sampled from the file")
+	}
+	payloads, err := perf.ReadPayloads(o.payloadFile, o.payloadDelimiter, rnd)
+	if err != nil {
+		return nil, utils.UsageError("--payload-file: %s", err)
+	}
+	return payloads, nil
+}
+
+func (o *produceOptions) run(cmd *cobra.Command, args []string) (err error) {
+	if o.topic == "" {
+		return utils.UsageError("empty topic")
+	}
+	if o.numRecords <= 0 {
+		return utils.UsageError("--num-records should be positive")
+	}
+	payloads, err := o.payloads(cmd)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := o.client.NewContext(cmd.Context())
+	defer cancel()
+	config, err := o.client.NewConfig()
+	if err != nil {
+		return err
+	}
+	config.Producer.Return.Successes = true
+
+	var producer sarama.AsyncProducer
+	err = kafka.WaitOpen(ctx, func() (err error) {
+		producer, err = kafka.NewAsyncProducer(o.client.Brokers(), config)
+		return err
+	}, func() { producer.AsyncClose() })
+	if err != nil {
+		return err
+	}
+	meter := perf.NewMeter()
+	r, err := o.report.startReporter(meter, true)
+	if err != nil {
+		producer.AsyncClose()
+		return err
+	}
+
+	// the latency of a record is the time from handing it to the producer to
+	// its acknowledgement, the time waiting for the producer's buffer included
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for msg := range producer.Successes() {
+			meter.RecordLatency(msg.Value.Length(), time.Since(msg.Metadata.(time.Time)))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for perr := range producer.Errors() {
+			meter.Error()
+			log.Warn("Record failed", zap.Error(perr.Err))
+		}
+	}()
+
+	throttle := perf.NewThrottle(o.throughput)
+	var sent int64
+send:
+	for ; sent < int64(o.numRecords); sent++ {
+		if err := throttle.Wait(ctx, sent); err != nil {
+			break
+		}
+		msg := &sarama.ProducerMessage{Topic: o.topic, Value: sarama.ByteEncoder(payloads.Next()), Metadata: time.Now()}
+		select {
+		case producer.Input() <- msg:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	// closing flushes the buffered records, every record handed to the
+	// producer is counted
+	producer.AsyncClose()
+	wg.Wait()
+	if ctx.Err() != nil {
+		log.Info("Perf test stopped early", zap.Int64("sent", sent), zap.Error(kafka.ContextError(ctx)))
+	}
+	total, err := r.stop()
+	if err != nil {
+		return err
+	}
+	if total.Errors > 0 {
+		return fmt.Errorf("%d of %d records failed", total.Errors, total.Records+total.Errors)
+	}
+	return nil
+}
+
+func NewCmdPerfProduce(clientOptions *kafka.ClientOptions) *cobra.Command {
+	o := newProduceOptions(clientOptions)
+	cmd := &cobra.Command{
+		Use:     "produce",
+		Short:   "Measure the producer throughput and latency",
+		Long:    "Produce records with an asynchronous producer, as fast as possible or at --throughput, and report the records and MB per second and the p50, p95, p99 and max latency at an interval and at the end. The producer is configured with --client-property, e.g. acks or compression.type",
+		Example: produceExample,
+		Args:    cobra.NoArgs,
+		RunE:    o.run,
+	}
+	o.report.addFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.topic, "topic", "", "REQUIRED: The topic to produce to")
+	cmd.Flags().Var(&o.numRecords, "num-records", "The number of records to produce, e.g. 1e6")
+	cmd.Flags().IntVar(&o.recordSize, "record-size", 1024, "The size of every record in bytes")
+	cmd.Flags().Float64Var(&o.throughput, "throughput", -1, "The most records per second to produce, -1 for no limit")
+	cmd.Flags().StringVar(&o.payload, "payload", perf.PayloadRandom, "The values to produce, random bytes which differ in every record or the same fixed letters every time")
+	cmd.Flags().StringVar(&o.payloadFile, "payload-file", "", "Produce values sampled at random from this file, split at --payload-delimiter, instead of --record-size bytes")
+	cmd.Flags().StringVar(&o.payloadDelimiter, "payload-delimiter", "\n", "The delimiter of the values of --payload-file")
+	return cmd
+}
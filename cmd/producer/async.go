@@ -0,0 +1,62 @@
+package producer
+
+import (
+	"context"
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/kafka"
+	"sync"
+)
+
+// produceAsync hands the records to an async producer without waiting for
+// them, the successes and errors are collected while records are sent.
+func (o *producerOptions) produceAsync(ctx context.Context, config *sarama.Config, records <-chan readResult,
+	prepare func(readResult) (batchRecord, error), res *results) error {
+	producer, err := kafka.NewAsyncProducer(o.client.Brokers(), config)
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for msg := range producer.Successes() {
+			res.success(msg)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for perr := range producer.Errors() {
+			rec := perr.Msg.Metadata.(*record)
+			res.failure(rec.line, rec.text, perr.Err)
+		}
+	}()
+	// closing flushes the buffered records, the results of every record
+	// handed to the producer are collected, also when interrupted
+	defer func() {
+		producer.AsyncClose()
+		wg.Wait()
+	}()
+	for {
+		select {
+		case next, ok := <-records:
+			if !ok {
+				return nil
+			}
+			r, err := prepare(next)
+			if err != nil {
+				return err
+			}
+			if r.err != nil {
+				res.failure(r.line, r.text, r.err)
+				continue
+			}
+			select {
+			case producer.Input() <- r.msg:
+			case <-ctx.Done():
+				return kafka.ContextError(ctx)
+			}
+		case <-ctx.Done():
+			return kafka.ContextError(ctx)
+		}
+	}
+}
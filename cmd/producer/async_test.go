@@ -0,0 +1,89 @@
+package producer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/kafka"
+)
+
+func TestProduceFileRejects(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	// every record of partition 1 is rejected by the broker
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("t", 0, broker.BrokerID()).
+			SetLeader("t", 1, broker.BrokerID()),
+		"ProduceRequest": sarama.NewMockProduceResponse(t).SetVersion(3).
+			SetError("t", 1, sarama.ErrMessageSizeTooLarge),
+	})
+
+	lines := []string{
+		`{"key": "a", "value": "1", "partition": 0}`,
+		`{"key": "b", "value": "2", "partition": 1}`,
+		`{"key": "c", "value": 3`,
+		`{"key": "d", "value": "4", "partition": 0}`,
+		`{"key": "e", "value": "5", "partition": 1}`,
+		`{"key": "f", "value": "6", "partition": -1}`,
+		`{"key": "g", "value": "7", "partition": 0}`,
+	}
+	// the lines which are not valid records and the records of partition 1
+	failed := []string{lines[1], lines[2], lines[4], lines[5]}
+
+	dir, err := ioutil.TempDir("", "producer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "records.ndjson")
+	if err := ioutil.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, async := range []bool{true, false} {
+		name := "sync"
+		if async {
+			name = "async"
+		}
+		t.Run(name, func(t *testing.T) {
+			rejects := filepath.Join(dir, name+".rejects")
+			args := []string{"--topic=t", "--file=" + file, "--input-format=ndjson", "--reject-file=" + rejects, "--retries=0"}
+			if async {
+				args = append(args, "--async")
+			}
+			cmd := NewCmdProducer(&kafka.ClientOptions{BootstrapServers: broker.Addr(), KafkaVersion: "0.11.0.0", Timeout: 5 * time.Second})
+			cmd.SilenceUsage, cmd.SilenceErrors = true, true
+			cmd.SetArgs(args)
+			err := cmd.Execute()
+			if err == nil || err.Error() != "4 of 7 records failed" {
+				t.Fatalf("err = %v, want 4 of 7 records failed", err)
+			}
+
+			got, err := ioutil.ReadFile(rejects)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rejected := strings.Split(strings.TrimSuffix(string(got), "\n"), "\n")
+			// the async producer reports the records as the broker answers,
+			// the reject file holds the failed lines in any order
+			if async {
+				order := map[string]int{}
+				for i, line := range lines {
+					order[line] = i
+				}
+				sort.Slice(rejected, func(i, j int) bool { return order[rejected[i]] < order[rejected[j]] })
+			}
+			if strings.Join(rejected, "\n") != strings.Join(failed, "\n") {
+				t.Errorf("reject file:\n%s\nwant:\n%s", got, strings.Join(failed, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package producer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/kafka"
+	"github.com/thimico/kafka-cli/serde"
+	"github.com/thimico/kafka-cli/utils"
+	"io"
+	"os"
+)
+
+// fileBatch is the most records sent at once, fewer are sent when no more
+// are read yet, so records typed on stdin are sent right away.
+const fileBatch = 500
+
+// recordPartitioner sends the records with a partition to it and leaves the
+// others to the partitioner of --partitioner.
+type recordPartitioner struct {
+	sarama.Partitioner
+}
+
+func (p recordPartitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if msg.Partition >= 0 {
+		return msg.Partition, nil
+	}
+	return p.Partitioner.Partition(msg, numPartitions)
+}
+
+// readResult is a record read by the reading goroutine, or the error of
+// reading it.
+type readResult struct {
+	rec *record
+	err error
+}
+
+// batchRecord is a record to send, err is set when it could not be parsed
+// or encoded.
+type batchRecord struct {
+	line int
+	text string
+	msg  *sarama.ProducerMessage
+	err  error
+}
+
+// produceFile produces every record of --file, a record which can not be
+// parsed, encoded or sent is counted as failed and the next is produced.
+func (o *producerOptions) produceFile(ctx context.Context, config *sarama.Config, headers []header) (err error) {
+	var in io.Reader = os.Stdin
+	if o.file != "-" {
+		var f *os.File
+		if f, err = os.Open(o.file); err != nil {
+			return utils.UsageError("--file: %s", err)
+		}
+		defer utils.Close(f, &err)
+		in = f
+	}
+	r := &recordReader{r: bufio.NewReader(in), format: o.inputFormat, keySeparator: o.keySeparator, headersSeparator: o.headersSeparator}
+	return o.produceRecords(ctx, config, headers, func(ctx context.Context, records chan<- readResult) {
+		for {
+			rec, err := r.read()
+			if err == io.EOF {
+				return
+			}
+			select {
+			case records <- readResult{rec: rec, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if _, ok := err.(*recordError); err != nil && !ok {
+				return
+			}
+		}
+	})
+}
+
+// produceRecords produces the records source sends until it returns, with
+// headers added to every record. source runs in its own goroutine and stops
+// once ctx is done.
+func (o *producerOptions) produceRecords(ctx context.Context, config *sarama.Config, headers []header,
+	source func(ctx context.Context, records chan<- readResult)) (err error) {
+	var reject io.Writer
+	if o.rejectFile != "" {
+		var f *os.File
+		if f, err = os.Create(o.rejectFile); err != nil {
+			return utils.UsageError("--reject-file: %s", err)
+		}
+		defer utils.Close(f, &err)
+		reject = f
+	}
+	base := config.Producer.Partitioner
+	config.Producer.Partitioner = func(topic string) sarama.Partitioner {
+		return recordPartitioner{base(topic)}
+	}
+	var encoder serde.Encoder
+	err = kafka.Wait(ctx, func() (err error) {
+		encoder, err = o.valueEncoder()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	queue := fileBatch
+	if o.async {
+		queue = o.queueSize
+	}
+	records := make(chan readResult, queue)
+	go func() {
+		defer close(records)
+		source(ctx, records)
+	}()
+	prepare := func(next readResult) (batchRecord, error) {
+		if next.err != nil {
+			rerr, ok := next.err.(*recordError)
+			if !ok {
+				return batchRecord{}, fmt.Errorf("read %s: %s", o.file, next.err)
+			}
+			return batchRecord{line: rerr.line, text: rerr.text, err: rerr.err}, nil
+		}
+		rec := next.rec
+		rec.headers = append(append([]header{}, headers...), rec.headers...)
+		if rec.partition < 0 {
+			rec.partition = o.partition
+		}
+		msg, err := o.message(rec, encoder)
+		return batchRecord{line: rec.line, text: rec.text, msg: msg, err: err}, nil
+	}
+
+	res := newResults(o.topic, o.printResults, reject)
+	defer func() {
+		if finishErr := res.finish(); err == nil {
+			err = finishErr
+		}
+	}()
+	if o.async {
+		return o.produceAsync(ctx, config, records, prepare, res)
+	}
+	return o.produceSync(ctx, config, records, prepare, res)
+}
+
+// produceSync sends the records in batches and waits for every batch.
+func (o *producerOptions) produceSync(ctx context.Context, config *sarama.Config, records <-chan readResult,
+	prepare func(readResult) (batchRecord, error), res *results) (err error) {
+	producer, err := kafka.NewProducer(o.client.Brokers(), config)
+	if err != nil {
+		return err
+	}
+	defer utils.Close(producer, &err)
+	for {
+		var read []readResult
+		select {
+		case next, ok := <-records:
+			if !ok {
+				return nil
+			}
+			read = append(read, next)
+		case <-ctx.Done():
+			return kafka.ContextError(ctx)
+		}
+		read, closed := readMore(records, read)
+		var batch []batchRecord
+		for _, next := range read {
+			r, err := prepare(next)
+			if err != nil {
+				return err
+			}
+			batch = append(batch, r)
+		}
+		sendBatch(producer, batch, res)
+		if closed {
+			return nil
+		}
+	}
+}
+
+// readMore adds the records which are already read to read, up to a full
+// batch, closed reports whether every record is read.
+func readMore(records <-chan readResult, read []readResult) (_ []readResult, closed bool) {
+	for len(read) < fileBatch {
+		select {
+		case next, ok := <-records:
+			if !ok {
+				return read, true
+			}
+			read = append(read, next)
+		default:
+			return read, false
+		}
+	}
+	return read, false
+}
+
+// sendBatch sends the records of a batch which have no error yet and reports
+// the result of every record in the order of the file.
+func sendBatch(producer sarama.SyncProducer, batch []batchRecord, res *results) {
+	var msgs []*sarama.ProducerMessage
+	for _, r := range batch {
+		if r.err == nil {
+			msgs = append(msgs, r.msg)
+		}
+	}
+	failures := map[*sarama.ProducerMessage]error{}
+	if len(msgs) > 0 {
+		if err := producer.SendMessages(msgs); err != nil {
+			errs, ok := err.(sarama.ProducerErrors)
+			if !ok {
+				for _, msg := range msgs {
+					failures[msg] = err
+				}
+			}
+			for _, e := range errs {
+				failures[e.Msg] = e.Err
+			}
+		}
+	}
+	for _, r := range batch {
+		if r.err == nil {
+			r.err = failures[r.msg]
+		}
+		if r.err != nil {
+			res.failure(r.line, r.text, r.err)
+			continue
+		}
+		res.success(r.msg)
+	}
+}
@@ -0,0 +1,91 @@
+package producer
+
+import (
+	"context"
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/generate"
+	"github.com/thimico/kafka-cli/perf"
+	"github.com/thimico/kafka-cli/utils"
+	"math/rand"
+	"text/template"
+	"time"
+)
+
+// produceGenerated produces --count records rendered from the templates of
+// --key, --value and --headers, at most --rate per second.
+func (o *producerOptions) produceGenerated(ctx context.Context, config *sarama.Config, headers []header) error {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	keys, err := generate.NewKeys(o.keyDistribution, o.keys, o.zipfExponent, rnd)
+	if err != nil {
+		return utils.UsageError("%s", err)
+	}
+	g := generate.New(rnd, keys)
+	var keyTemplate *template.Template
+	if o.key != "" {
+		if keyTemplate, err = g.Parse("key", o.key); err != nil {
+			return utils.UsageError("--key: %s", err)
+		}
+	}
+	valueTemplate, err := g.Parse("value", o.value)
+	if err != nil {
+		return utils.UsageError("--value: %s", err)
+	}
+	headerTemplates := make([]*template.Template, len(headers))
+	for i, h := range headers {
+		if h.value == nil {
+			continue
+		}
+		if headerTemplates[i], err = g.Parse("header "+h.key, *h.value); err != nil {
+			return utils.UsageError("--headers: %s: %s", h.key, err)
+		}
+	}
+
+	// render returns the current record of g, nil templates stay null
+	render := func(line int) (*record, error) {
+		rec := &record{line: line, partition: -1}
+		if keyTemplate != nil {
+			key, err := g.Render(keyTemplate)
+			if err != nil {
+				return nil, err
+			}
+			rec.key = &key
+		}
+		value, err := g.Render(valueTemplate)
+		if err != nil {
+			return nil, err
+		}
+		rec.value = &value
+		rec.text = value
+		for i, h := range headers {
+			rh := header{key: h.key}
+			if headerTemplates[i] != nil {
+				v, err := g.Render(headerTemplates[i])
+				if err != nil {
+					return nil, err
+				}
+				rh.value = &v
+			}
+			rec.headers = append(rec.headers, rh)
+		}
+		return rec, nil
+	}
+	throttle := perf.NewThrottle(o.rate)
+	return o.produceRecords(ctx, config, nil, func(ctx context.Context, records chan<- readResult) {
+		for n := int64(0); o.count == 0 || n < o.count; n++ {
+			if throttle.Wait(ctx, n) != nil {
+				return
+			}
+			g.Next()
+			rec, err := render(int(n + 1))
+			next := readResult{rec: rec}
+			if err != nil {
+				next = readResult{err: &recordError{line: int(n + 1), err: err}}
+			}
+			select {
+			case records <- next:
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
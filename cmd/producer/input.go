@@ -0,0 +1,229 @@
+package producer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The formats of --file.
+const (
+	inputLines  = "lines"
+	inputNDJSON = "ndjson"
+)
+
+// record is a record read from --file. The key, value and header values are
+// text in their formats, nil for null.
+type record struct {
+	line      int
+	text      string
+	key       *string
+	value     *string
+	headers   []header
+	partition int32
+}
+
+type header struct {
+	key   string
+	value *string
+}
+
+// recordError is a record which can not be parsed, the records after it
+// are still read.
+type recordError struct {
+	line int
+	text string
+	err  error
+}
+
+func (e *recordError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.err)
+}
+
+// recordReader reads the records of --file, read returns io.EOF at the end
+// and a *recordError for a record which can not be parsed.
+type recordReader struct {
+	r    *bufio.Reader
+	line int
+
+	format           string
+	keySeparator     string
+	headersSeparator string
+}
+
+func (r *recordReader) read() (*record, error) {
+	for {
+		text, err := r.r.ReadString('\n')
+		if err != nil && (err != io.EOF || text == "") {
+			return nil, err
+		}
+		r.line++
+		text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+		if text == "" {
+			continue
+		}
+		var rec *record
+		if r.format == inputNDJSON {
+			rec, err = parseNDJSON(text)
+		} else {
+			rec, err = r.parseLine(text)
+		}
+		if err != nil {
+			return nil, &recordError{line: r.line, text: text, err: err}
+		}
+		rec.line = r.line
+		rec.text = text
+		return rec, nil
+	}
+}
+
+// parseLine parses [headers<headers-separator>][key<key-separator>]value,
+// the headers are a list like the one of --headers.
+func (r *recordReader) parseLine(text string) (*record, error) {
+	rec := &record{partition: -1}
+	if r.headersSeparator != "" {
+		i := strings.Index(text, r.headersSeparator)
+		if i < 0 {
+			return nil, fmt.Errorf("no headers separator %q", r.headersSeparator)
+		}
+		headers, err := parseHeaders(text[:i])
+		if err != nil {
+			return nil, err
+		}
+		rec.headers = headers
+		text = text[i+len(r.headersSeparator):]
+	}
+	if r.keySeparator != "" {
+		i := strings.Index(text, r.keySeparator)
+		if i < 0 {
+			return nil, fmt.Errorf("no key separator %q", r.keySeparator)
+		}
+		key := text[:i]
+		rec.key = &key
+		text = text[i+len(r.keySeparator):]
+	}
+	rec.value = &text
+	return rec, nil
+}
+
+// parseHeaders parses a list of headers like foo:bar,bar:foo.
+func parseHeaders(s string) ([]header, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var headers []header
+	for _, h := range strings.Split(s, ",") {
+		kv := strings.Split(h, ":")
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("header %q should be key:value, e.g. foo:bar,bar:foo", h)
+		}
+		value := kv[1]
+		headers = append(headers, header{key: kv[0], value: &value})
+	}
+	return headers, nil
+}
+
+// ndjsonRecord is a record of the ndjson format. Keys, values and header
+// values which are not strings are taken as their json text, headers are an
+// object or a list of key and value objects like the consumer output.
+type ndjsonRecord struct {
+	Key       json.RawMessage `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	Headers   json.RawMessage `json:"headers"`
+	Partition *int32          `json:"partition"`
+}
+
+func parseNDJSON(text string) (*record, error) {
+	var nr ndjsonRecord
+	if err := json.Unmarshal([]byte(text), &nr); err != nil {
+		return nil, err
+	}
+	rec := &record{partition: -1}
+	var err error
+	if rec.key, err = jsonText(nr.Key); err != nil {
+		return nil, fmt.Errorf("key: %s", err)
+	}
+	if rec.value, err = jsonText(nr.Value); err != nil {
+		return nil, fmt.Errorf("value: %s", err)
+	}
+	if rec.headers, err = jsonHeaders(nr.Headers); err != nil {
+		return nil, fmt.Errorf("headers: %s", err)
+	}
+	if nr.Partition != nil {
+		if *nr.Partition < 0 {
+			return nil, fmt.Errorf("invalid partition %d", *nr.Partition)
+		}
+		rec.partition = *nr.Partition
+	}
+	return rec, nil
+}
+
+// jsonText returns a json string as is, null as nil and anything else as
+// its compact json text.
+func jsonText(raw json.RawMessage) (*string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var s string
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		return &s, nil
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return nil, err
+	}
+	s = buf.String()
+	return &s, nil
+}
+
+func jsonHeaders(raw json.RawMessage) ([]header, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var headers []header
+	if raw[0] == '[' {
+		var list []struct {
+			Key   string          `json:"key"`
+			Value json.RawMessage `json:"value"`
+		}
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+		for _, h := range list {
+			value, err := jsonText(h.Value)
+			if err != nil {
+				return nil, err
+			}
+			headers = append(headers, header{key: h.Key, value: value})
+		}
+		return headers, nil
+	}
+	// the object is read token by token to keep the order of the headers
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, errors.New("should be an object or a list")
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		value, err := jsonText(v)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header{key: t.(string), value: value})
+	}
+	return headers, nil
+}
@@ -0,0 +1,139 @@
+package producer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func str(s string) *string {
+	return &s
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name             string
+		keySeparator     string
+		headersSeparator string
+		text             string
+		want             *record
+		err              string
+	}{
+		{"value", "", "", "a:b|c", &record{value: str("a:b|c"), partition: -1}, ""},
+		{"key", ":", "", "k:v:w", &record{key: str("k"), value: str("v:w"), partition: -1}, ""},
+		{"empty key", ":", "", ":v", &record{key: str(""), value: str("v"), partition: -1}, ""},
+		{"empty value", ":", "", "k:", &record{key: str("k"), value: str(""), partition: -1}, ""},
+		{"long separator", "::", "", "a:b::c", &record{key: str("a:b"), value: str("c"), partition: -1}, ""},
+		{"headers", ":", "|", "source:web,id:1|k:v", &record{
+			headers: []header{{"source", str("web")}, {"id", str("1")}}, key: str("k"), value: str("v"), partition: -1}, ""},
+		{"no headers", ":", "|", "|k:v", &record{key: str("k"), value: str("v"), partition: -1}, ""},
+		{"empty header value", "", "|", "source:|v", &record{headers: []header{{"source", str("")}}, value: str("v"), partition: -1}, ""},
+
+		{"missing key separator", ":", "", "v", nil, `no key separator ":"`},
+		{"missing headers separator", ":", "|", "k:v", nil, `no headers separator "|"`},
+		{"invalid header", "", "|", "source|v", nil, `header "source" should be key:value`},
+		{"header with two colons", "", "|", "a:b:c|v", nil, `header "a:b:c" should be key:value`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recordReader{keySeparator: tt.keySeparator, headersSeparator: tt.headersSeparator}
+			got, err := r.parseLine(tt.text)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %s, want %s", describe(got), describe(tt.want))
+			}
+		})
+	}
+}
+
+func TestParseNDJSON(t *testing.T) {
+	tests := []struct {
+		text string
+		want *record
+		err  string
+	}{
+		{`{"key":"k","value":"v"}`, &record{key: str("k"), value: str("v"), partition: -1}, ""},
+		{`{"value":"v"}`, &record{value: str("v"), partition: -1}, ""},
+		{`{"key":null,"value":null}`, &record{partition: -1}, ""},
+		{`{"key":42,"value":{"id": 1, "tags": ["a"]}}`, &record{key: str("42"), value: str(`{"id":1,"tags":["a"]}`), partition: -1}, ""},
+		{`{"value":"line\nbreak \"quoted\""}`, &record{value: str("line\nbreak \"quoted\""), partition: -1}, ""},
+		{`{"value":"v","partition":3}`, &record{value: str("v"), partition: 3}, ""},
+		{`{"value":"v","headers":{"b":"2","a":1,"c":null}}`, &record{value: str("v"), partition: -1,
+			headers: []header{{"b", str("2")}, {"a", str("1")}, {"c", nil}}}, ""},
+		{`{"value":"v","headers":[{"key":"a","value":"1"},{"key":"a","value":true}]}`, &record{value: str("v"), partition: -1,
+			headers: []header{{"a", str("1")}, {"a", str("true")}}}, ""},
+		{`{"value":"v","headers":null}`, &record{value: str("v"), partition: -1}, ""},
+
+		{`{"value":`, nil, "unexpected end of JSON input"},
+		{`["v"]`, nil, "cannot unmarshal array"},
+		{`{"value":"v","partition":-1}`, nil, "invalid partition -1"},
+		{`{"value":"v","partition":"1"}`, nil, "cannot unmarshal string"},
+		{`{"value":"v","headers":"a:1"}`, nil, "headers: should be an object or a list"},
+		{`{"value":"v","headers":[{"key":1}]}`, nil, "headers: json: cannot unmarshal number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := parseNDJSON(tt.text)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %s, want %s", describe(got), describe(tt.want))
+			}
+		})
+	}
+}
+
+func TestRecordReader(t *testing.T) {
+	r := &recordReader{r: bufio.NewReader(strings.NewReader("k1:v1\r\n\nbad\nk2:v2")), keySeparator: ":"}
+	rec, err := r.read()
+	if err != nil || rec.line != 1 || *rec.key != "k1" || *rec.value != "v1" {
+		t.Fatalf("first record %s, %v", describe(rec), err)
+	}
+	// blank lines are skipped but counted
+	_, err = r.read()
+	if recErr, ok := err.(*recordError); !ok || recErr.line != 3 || recErr.text != "bad" {
+		t.Fatalf("err = %v, want a record error of line 3", err)
+	}
+	rec, err = r.read()
+	if err != nil || rec.line != 4 || rec.text != "k2:v2" || *rec.value != "v2" {
+		t.Fatalf("last record %s, %v", describe(rec), err)
+	}
+	if _, err := r.read(); err != io.EOF {
+		t.Errorf("err = %v, want EOF", err)
+	}
+}
+
+func describe(r *record) string {
+	if r == nil {
+		return "<nil>"
+	}
+	text := func(s *string) string {
+		if s == nil {
+			return "null"
+		}
+		return "\"" + *s + "\""
+	}
+	var headers []string
+	for _, h := range r.headers {
+		headers = append(headers, h.key+"="+text(h.value))
+	}
+	return fmt.Sprintf("{key %s value %s headers [%s] partition %d}", text(r.key), text(r.value), strings.Join(headers, " "), r.partition)
+}
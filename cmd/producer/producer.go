@@ -1,14 +1,19 @@
 package producer
 
 import (
-	"errors"
+	"fmt"
 	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/generate"
 	"github.com/thimico/kafka-cli/kafka"
 	"github.com/thimico/kafka-cli/log"
+	"github.com/thimico/kafka-cli/serde"
 	"github.com/thimico/kafka-cli/utils"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"strings"
+	"time"
 )
 
 var producerExample = `
@@ -16,99 +21,449 @@ var producerExample = `
     ./kafka-cli producer --bootstrap-servers=localhost:9092 --key=13 --partitioner=random --topic=singed --value='test value'
     result:
         {"level":"info","ts":1612429377.79058,"caller":"log/log.go:16","msg":"Send message success","partition":2,"offset":0}
+
+# Produce a big endian int64 key, a binary value and a uuid header, the way the consumer prints them with the same formats
+    ./kafka-cli producer --topic=accounts --key-format=int64 --key=42 --value-format=hex --value=cafe01 --header-format=uuid --headers=trace:0b7f4c5e-2a52-4c4e-9a1c-3f0f9e1a6b2d
+
+# Produce a tombstone
+    ./kafka-cli producer --topic=accounts --key-format=int64 --key=42 --value-format=null
+
+# Produce every line of a file, the key before the first colon, and print where every record went
+    ./kafka-cli producer --topic=singed --file=records.txt --key-separator=: --print-results
+
+# Produce from stdin, with the headers before a | and the key before a tab
+    printf 'source:web|user-1\t{"id": 1}\n' | ./kafka-cli producer --topic=singed --file=- --headers-separator='|' --key-separator=$'\t'
+
+# Produce a big file as fast as possible, the failed lines are written to rejects.txt
+    ./kafka-cli producer --topic=singed --file=records.txt --async --batch-messages=1000 --reject-file=rejects.txt
+
+# Produce the way a service does, its settings are logged with --verbose
+    ./kafka-cli producer --topic=singed --value=test --acks=1 --compression=lz4 --linger=5ms --batch-size=16384 --verbose
+
+# Produce exactly once per partition with the idempotent producer
+    ./kafka-cli producer --topic=singed --file=records.txt --idempotent --kafka-version=2.6.0
+
+# Seed a topic with 10000 fake users at 500 records per second, the keys of a few users are hot
+    ./kafka-cli producer --topic=users --generate --count=10000 --rate=500 --key='user-{{key}}' --key-distribution=zipf --keys=1000 --value='{"id": {{seq}}, "name": "{{name}}", "email": "{{email}}", "address": "{{address}}", "score": {{int 1 100}}}' --headers='trace:{{uuid}}'
+
+# Produce json objects with key, value, headers and partition, e.g. the ndjson output of the consumer
+    ./kafka-cli consumer --topic=singed --offset=oldest --exit-on-eof -o ndjson | ./kafka-cli producer --topic=singed-copy --file=- --input-format=ndjson
+
+# Produce an avro value from its json encoding, with the latest schema of the subject orders-value
+    ./kafka-cli producer --topic=orders --value-format=avro --schema-registry-url=http://localhost:8081 --value='{"id": 1, "status": "NEW"}'
+
+# Produce an avro value with the schema of a given id
+    ./kafka-cli producer --topic=orders --value-schema-id=42 --value='{"id": 1, "status": "NEW"}'
+
+# Produce a protobuf value from its json mapping, in the schema registry wire format of schema 43
+    ./kafka-cli producer --topic=orders --value-format=protobuf --proto-descriptor-set=shop.pb --proto-message=shop.v1.Order --value-schema-id=43 --value='{"id": "1", "status": "NEW"}'
 `
 
 type producerOptions struct {
-	bootstrapServers string
+	client *kafka.ClientOptions
+
 	topic            string
 	key              string
 	value            string
 	headers          string
 	partitioner      string
 	partition        int32
+	keyFormat        string
+	valueFormat      string
+	headerFormat     string
+	valueSchemaID    int
+	valueSubject     string
+	file             string
+	inputFormat      string
+	keySeparator     string
+	headersSeparator string
+	printResults     bool
+	rejectFile       string
+	async            bool
+	maxInFlight      int
+	queueSize        int
+	batchMessages    int
+	acks             string
+	compression      string
+	compressionLevel int
+	idempotent       bool
+	retries          int
+	retryBackoff     time.Duration
+	linger           time.Duration
+	batchSize        int
+	generate         bool
+	count            int64
+	rate             float64
+	keyDistribution  string
+	keys             uint64
+	zipfExponent     float64
+
+	protoDescriptorSet string
+	protoMessage       string
+	protoFiles         *protoregistry.Files
+	keyEncoder         serde.Encoder
+	headerEncoder      serde.Encoder
 }
 
-func newProducerOptions() *producerOptions {
-	return &producerOptions{}
+func newProducerOptions(clientOptions *kafka.ClientOptions) *producerOptions {
+	return &producerOptions{client: clientOptions}
 }
 
 func (o *producerOptions) validate() error {
 	if o.topic == "" {
-		return errors.New("empty topic")
+		return utils.UsageError("empty topic")
+	}
+	if o.generate {
+		if o.file != "" {
+			return utils.UsageError("--generate and --file are mutually exclusive")
+		}
+		if o.count < 0 || o.rate < 0 {
+			return utils.UsageError("--count and --rate should not be negative")
+		}
+		if o.queueSize <= 0 {
+			return utils.UsageError("--queue-size should be positive")
+		}
+	}
+	if o.file != "" {
+		if o.key != "" || o.value != "" {
+			return utils.UsageError("--key and --value can not be used with --file, the records are read from the file")
+		}
+		switch o.inputFormat {
+		case inputLines:
+		case inputNDJSON:
+			if o.keySeparator != "" || o.headersSeparator != "" {
+				return utils.UsageError("--key-separator and --headers-separator only apply to --input-format=lines")
+			}
+		default:
+			return utils.UsageError("invalid --input-format %q, should be lines or ndjson", o.inputFormat)
+		}
+		if o.queueSize <= 0 {
+			return utils.UsageError("--queue-size should be positive")
+		}
+	} else if o.rejectFile != "" || o.async && !o.generate {
+		return utils.UsageError("--reject-file needs --file and --async needs --file or --generate")
+	} else if o.value == "" && o.valueFormat != serde.FormatNull {
+		return utils.UsageError("empty value, use --value-format=null for a null value")
+	}
+	var err error
+	if o.keyEncoder, err = serde.NewFormat(o.keyFormat); err != nil {
+		return utils.UsageError("--key-format: %s", err)
+	}
+	if o.headerEncoder, err = serde.NewFormat(o.headerFormat); err != nil {
+		return utils.UsageError("--header-format: %s", err)
 	}
-	if o.value == "" {
-		return errors.New("empty value")
+	if o.valueSchemaID >= 0 && o.valueSubject != "" {
+		return utils.UsageError("--value-schema-id and --value-subject are mutually exclusive")
+	}
+	if (o.valueSchemaID >= 0 || o.valueSubject != "") && o.valueFormat == serde.FormatString {
+		o.valueFormat = serde.FormatAvro
+	}
+	switch o.valueFormat {
+	case serde.FormatAvro:
+		if o.client.SchemaRegistryURL == "" {
+			return utils.UsageError("the avro format needs --schema-registry-url")
+		}
+		if o.valueSchemaID < 0 && o.valueSubject == "" {
+			o.valueSubject = o.topic + "-value"
+		}
+	case serde.FormatProtobuf:
+		if o.protoDescriptorSet == "" || o.protoMessage == "" {
+			return utils.UsageError("the protobuf format needs --proto-descriptor-set and --proto-message")
+		}
+		if o.valueSubject != "" && o.client.SchemaRegistryURL == "" {
+			return utils.UsageError("--value-subject needs --schema-registry-url")
+		}
+		files, err := serde.LoadDescriptorSet(o.protoDescriptorSet)
+		if err != nil {
+			return utils.UsageError("--proto-descriptor-set: %s", err)
+		}
+		o.protoFiles = files
+	default:
+		if _, err := serde.NewFormat(o.valueFormat); err != nil {
+			return utils.UsageError("unknown value format %q, should be one of %s", o.valueFormat, strings.Join(serde.Formats, ", "))
+		}
 	}
 	return nil
 }
 
-func (o *producerOptions) run(cmd *cobra.Command, args []string) {
+// valueEncoder returns the encoder of --value-format. Avro values are
+// encoded with the schema of --value-schema-id or the latest schema of
+// --value-subject, protobuf values are written in the wire format of that
+// schema if one is given.
+func (o *producerOptions) valueEncoder() (serde.Encoder, error) {
+	switch o.valueFormat {
+	case serde.FormatAvro:
+		return serde.NewAvroEncoder(o.client.SchemaRegistry(), o.valueSchemaID, o.valueSubject)
+	case serde.FormatProtobuf:
+		id := o.valueSchemaID
+		if o.valueSubject != "" {
+			schema, err := o.client.SchemaRegistry().LatestSchema(o.valueSubject)
+			if err != nil {
+				return nil, err
+			}
+			id = schema.ID
+		}
+		return serde.NewProtoEncoder(o.protoFiles, o.protoMessage, id)
+	}
+	return serde.NewFormat(o.valueFormat)
+}
 
-	err := o.validate()
+func (o *producerOptions) run(cmd *cobra.Command, args []string) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+	ctx, cancel := o.client.NewContext(cmd.Context())
+	defer cancel()
+	config, err := o.client.NewConfig()
 	if err != nil {
-		log.Info("kafka producer validate flags failed", zap.String("reason", err.Error()))
-		return
+		return err
 	}
-	config := sarama.NewConfig()
 	if o.partitioner == "random" {
 		config.Producer.Partitioner = sarama.NewRandomPartitioner
 	} else if o.partition >= 0 {
 		config.Producer.Partitioner = sarama.NewManualPartitioner
 	}
 
-	config.Producer.RequiredAcks = sarama.WaitForAll
 	config.Producer.Return.Successes = true
+	if err := o.applyBatchConfig(cmd.Flags(), config); err != nil {
+		return err
+	}
+	if err := o.applyDeliveryConfig(cmd.Flags(), config); err != nil {
+		return err
+	}
+	if o.client.Verbose {
+		logProducerConfig(config)
+	}
 
-	producer, err := kafka.NewProducer(strings.Split(o.bootstrapServers, ","), config)
-	utils.CheckErr(err)
-	defer func() {
-		utils.CheckErr(producer.Close())
-	}()
-
-	msg := sarama.ProducerMessage{
-		Topic: o.topic,
-		Value: sarama.StringEncoder(o.value),
-		//Metadata interface{}
-		Partition: o.partition,
+	headers, err := parseHeaders(o.headers)
+	if err != nil {
+		return utils.UsageError("--headers: %s", err)
 	}
+	if o.generate {
+		return o.produceGenerated(ctx, config, headers)
+	}
+	if o.file != "" {
+		return o.produceFile(ctx, config, headers)
+	}
+	rec := &record{value: &o.value, headers: headers, partition: o.partition}
 	if o.key != "" {
-		msg.Key = sarama.StringEncoder(o.key)
-	}
-	if o.headers != "" {
-		var hdrs []sarama.RecordHeader
-		arrHdrs := strings.Split(o.headers, ",")
-		for _, h := range arrHdrs {
-			if header := strings.Split(h, ":"); len(header) != 2 {
-				utils.CheckErr(errors.New("-header should be key:value. Example: -headers=foo:bar,bar:foo"))
-			} else {
-				hdrs = append(hdrs, sarama.RecordHeader{
-					Key:   []byte(header[0]),
-					Value: []byte(header[1]),
-				})
-			}
+		rec.key = &o.key
+	}
+	return kafka.Wait(ctx, func() error {
+		encoder, err := o.valueEncoder()
+		if err != nil {
+			return err
+		}
+		msg, err := o.message(rec, encoder)
+		if err != nil {
+			return utils.UsageError("%s", err)
+		}
+		return o.send(config, msg)
+	})
+}
+
+// applyBatchConfig applies the batching flags, only when given, so they do not
+// override --client-property.
+func (o *producerOptions) applyBatchConfig(flags *pflag.FlagSet, config *sarama.Config) error {
+	if flags.Changed("max-in-flight") {
+		if o.maxInFlight <= 0 {
+			return utils.UsageError("--max-in-flight should be positive")
+		}
+		config.Net.MaxOpenRequests = o.maxInFlight
+	}
+	if flags.Changed("batch-messages") {
+		if o.batchMessages < 0 {
+			return utils.UsageError("--batch-messages should not be negative")
 		}
-		if len(hdrs) != 0 {
-			msg.Headers = hdrs
+		config.Producer.Flush.Messages = o.batchMessages
+	}
+	if flags.Changed("linger") {
+		if o.linger < 0 {
+			return utils.UsageError("--linger should not be negative")
+		}
+		config.Producer.Flush.Frequency = o.linger
+	}
+	if flags.Changed("batch-size") {
+		if o.batchSize < 0 {
+			return utils.UsageError("--batch-size should not be negative")
 		}
+		config.Producer.Flush.Bytes = o.batchSize
+	}
+	return nil
+}
+
+// applyDeliveryConfig applies the acks, compression, retry and idempotence
+// flags, only when given like the batching flags, and checks the resulting
+// config before connecting.
+func (o *producerOptions) applyDeliveryConfig(flags *pflag.FlagSet, config *sarama.Config) error {
+	if flags.Changed("acks") {
+		acks, err := kafka.ParseRequiredAcks(o.acks)
+		if err != nil {
+			return utils.UsageError("--acks: %s", err)
+		}
+		config.Producer.RequiredAcks = acks
+	}
+	if flags.Changed("compression") {
+		codec, err := kafka.ParseCompression(o.compression)
+		if err != nil {
+			return utils.UsageError("--compression: %s", err)
+		}
+		config.Producer.Compression = codec
+	}
+	if flags.Changed("compression-level") {
+		config.Producer.CompressionLevel = o.compressionLevel
+	}
+	if flags.Changed("retries") {
+		if o.retries < 0 {
+			return utils.UsageError("--retries should not be negative")
+		}
+		config.Producer.Retry.Max = o.retries
+	}
+	if flags.Changed("retry-backoff") {
+		if o.retryBackoff < 0 {
+			return utils.UsageError("--retry-backoff should not be negative")
+		}
+		config.Producer.Retry.Backoff = o.retryBackoff
+	}
+	if flags.Changed("idempotent") {
+		config.Producer.Idempotent = o.idempotent
+		// sarama keeps the order of a single request in flight only
+		if o.idempotent && !flags.Changed("max-in-flight") {
+			config.Net.MaxOpenRequests = 1
+		}
+	}
+	if p := config.Producer; p.Idempotent {
+		switch {
+		case !config.Version.IsAtLeast(sarama.V0_11_0_0):
+			return utils.UsageError("--idempotent needs --kafka-version 0.11.0 or later, got %s", config.Version)
+		case p.RequiredAcks != sarama.WaitForAll:
+			return utils.UsageError("--idempotent needs --acks=all")
+		case p.Retry.Max < 1:
+			return utils.UsageError("--idempotent needs --retries of at least 1")
+		case config.Net.MaxOpenRequests != 1:
+			return utils.UsageError("--idempotent needs --max-in-flight=1")
+		}
+	}
+	if p := config.Producer; p.Compression == sarama.CompressionZSTD && !config.Version.IsAtLeast(sarama.V2_1_0_0) {
+		return utils.UsageError("--compression=zstd needs --kafka-version 2.1.0 or later, got %s", config.Version)
+	}
+	if err := config.Validate(); err != nil {
+		return utils.UsageError("%s", err)
+	}
+	return nil
+}
+
+// logProducerConfig logs the effective delivery and batching settings, after
+// the client properties and the flags are applied.
+func logProducerConfig(config *sarama.Config) {
+	p := config.Producer
+	log.Info("Producer config", zap.String("acks", kafka.RequiredAcksName(p.RequiredAcks)),
+		zap.String("compression", p.Compression.String()), zap.Int("compressionLevel", p.CompressionLevel),
+		zap.Bool("idempotent", p.Idempotent), zap.Int("retries", p.Retry.Max), zap.Duration("retryBackoff", p.Retry.Backoff),
+		zap.Duration("linger", p.Flush.Frequency), zap.Int("batchSize", p.Flush.Bytes), zap.Int("batchMessages", p.Flush.Messages),
+		zap.Int("maxInFlight", config.Net.MaxOpenRequests), zap.Int("maxMessageBytes", p.MaxMessageBytes),
+		zap.Duration("timeout", p.Timeout), zap.String("version", config.Version.String()))
+}
+
+// message encodes a record with the formats of the key, value and headers.
+func (o *producerOptions) message(rec *record, valueEncoder serde.Encoder) (*sarama.ProducerMessage, error) {
+	msg := &sarama.ProducerMessage{
+		Topic:     o.topic,
+		Partition: rec.partition,
+		Metadata:  rec,
+	}
+	if rec.key != nil {
+		key, err := o.keyEncoder.Encode(*rec.key)
+		if err != nil {
+			return nil, fmt.Errorf("key: %s", err)
+		}
+		if key != nil {
+			msg.Key = sarama.ByteEncoder(key)
+		}
+	}
+	if rec.value != nil {
+		value, err := valueEncoder.Encode(*rec.value)
+		if err != nil {
+			return nil, fmt.Errorf("value: %s", err)
+		}
+		if value != nil {
+			msg.Value = sarama.ByteEncoder(value)
+		}
+	}
+	for _, h := range rec.headers {
+		header := sarama.RecordHeader{Key: []byte(h.key)}
+		if h.value != nil {
+			value, err := o.headerEncoder.Encode(*h.value)
+			if err != nil {
+				return nil, fmt.Errorf("header %s: %s", h.key, err)
+			}
+			header.Value = value
+		}
+		msg.Headers = append(msg.Headers, header)
+	}
+	return msg, nil
+}
+
+func (o *producerOptions) send(config *sarama.Config, msg *sarama.ProducerMessage) (err error) {
+	producer, err := kafka.NewProducer(o.client.Brokers(), config)
+	if err != nil {
+		return err
+	}
+	defer utils.Close(producer, &err)
+
+	partition, offset, err := producer.SendMessage(msg)
+	if err != nil {
+		return err
 	}
-	partition, offset, err := producer.SendMessage(&msg)
 	log.Info("Send message success", zap.Int32("partition", partition), zap.Int64("offset", offset))
+	return nil
 }
-func NewCmdProducer() *cobra.Command {
-	o := newProducerOptions()
+
+func NewCmdProducer(clientOptions *kafka.ClientOptions) *cobra.Command {
+	o := newProducerOptions(clientOptions)
 	cmd := &cobra.Command{
 		Use:     "producer",
-		Short:   "A kafka synchronous producer",
-		Long:    "A kafka synchronous producer, with pretty much config options. but it's not asynchronous, which means it will wait for result before return",
+		Short:   "A kafka producer",
+		Long:    "A kafka producer, with pretty much config options. It is synchronous by default, which means it will wait for result before return, --async sends the records of --file or --generate in the background",
 		Example: producerExample,
-		Run:     o.run,
+		RunE:    o.run,
 	}
-	cmd.Flags().StringVarP(&o.bootstrapServers, "bootstrap-servers", "b", "localhost:9092", "The Kafka server to connect to.more than one should be separated by commas")
 	cmd.Flags().StringVar(&o.topic, "topic", o.topic, "REQUIRED: The topic id to produce messages to.")
 	cmd.Flags().StringVar(&o.key, "key", "", "the key of message")
 	cmd.Flags().StringVar(&o.value, "value", "", "REQUIRED: The message content which is going to be produced")
 	cmd.Flags().StringVar(&o.partitioner, "partitioner", "hash", "The partitioning scheme to use. Can be hash, manual, or random")
 	cmd.Flags().Int32Var(&o.partition, "partition", -1, "The partition which message produce to, if provided, it will use manual partitioner")
 	cmd.Flags().StringVar(&o.headers, "headers", "", "The headers of the message. Example: -headers=foo:bar,bar:foo")
+	cmd.Flags().StringVar(&o.file, "file", "", "Produce every line of this file, - for stdin, instead of --key and --value")
+	cmd.Flags().StringVar(&o.inputFormat, "input-format", inputLines, "The format of --file, lines of [headers<headers-separator>][key<key-separator>]value, or ndjson objects like {\"key\": .., \"value\": .., \"headers\": {..}, \"partition\": ..}")
+	cmd.Flags().StringVar(&o.keySeparator, "key-separator", "", "Split the key from the value of every line at the first occurrence of this separator, e.g. ':' (default no key)")
+	cmd.Flags().StringVar(&o.headersSeparator, "headers-separator", "", "Split the headers, a list like --headers, from the rest of every line at the first occurrence of this separator, e.g. '|' (default no headers)")
+	cmd.Flags().BoolVar(&o.printResults, "print-results", false, "Print the partition and offset, or the error, of every record of --file or --generate")
+	cmd.Flags().StringVar(&o.rejectFile, "reject-file", "", "Write the lines of --file which failed to this file, to produce them again later")
+	cmd.Flags().BoolVar(&o.async, "async", false, "Send the records of --file or --generate without waiting for every batch, for high throughput")
+	cmd.Flags().IntVar(&o.queueSize, "queue-size", 10000, "The most records of --async read ahead of the producer")
+	cmd.Flags().IntVar(&o.maxInFlight, "max-in-flight", 5, "The most unacknowledged requests per broker connection")
+	cmd.Flags().IntVar(&o.batchMessages, "batch-messages", 0, "Send a batch once it has this many records, 0 sends as soon as possible")
+	cmd.Flags().DurationVar(&o.linger, "linger", 0, "Wait up to this long for more records before sending a batch, like linger.ms, 0 sends as soon as possible")
+	cmd.Flags().IntVar(&o.batchSize, "batch-size", 0, "Send a batch once it has this many bytes, like batch.size, 0 sends as soon as possible")
+	cmd.Flags().StringVar(&o.acks, "acks", "all", "The acknowledgements to wait for, 0 for none, 1 for the leader or all for every in sync replica")
+	cmd.Flags().StringVar(&o.compression, "compression", "none", "The compression codec of the batches, one of none, gzip, snappy, lz4 or zstd")
+	cmd.Flags().IntVar(&o.compressionLevel, "compression-level", 0, "The level of --compression, e.g. 1-9 for gzip, the default of the codec when not given")
+	cmd.Flags().BoolVar(&o.idempotent, "idempotent", false, "Enable the idempotent producer, needs --acks=all, --retries of at least 1, --max-in-flight=1, the default with it, and kafka 0.11.0 or later")
+	cmd.Flags().IntVar(&o.retries, "retries", 3, "How many times a failed request is retried")
+	cmd.Flags().DurationVar(&o.retryBackoff, "retry-backoff", 100*time.Millisecond, "How long to wait before retrying a failed request")
+	cmd.Flags().BoolVar(&o.generate, "generate", false, "Produce synthetic records, --key, --value and the header values are templates like '{\"id\": {{seq}}, \"name\": \"{{name}}\"}'")
+	cmd.Flags().Int64Var(&o.count, "count", 0, "The number of records of --generate, 0 until interrupted")
+	cmd.Flags().Float64Var(&o.rate, "rate", 0, "The most records of --generate per second, 0 for no limit")
+	cmd.Flags().StringVar(&o.keyDistribution, "key-distribution", generate.DistributionUniform, "The distribution of {{key}} in the templates of --generate, uniform or zipf, where the low ids are hot keys")
+	cmd.Flags().Uint64Var(&o.keys, "keys", 1000, "The number of distinct {{key}} ids of --generate")
+	cmd.Flags().Float64Var(&o.zipfExponent, "zipf-exponent", 1.1, "How skewed --key-distribution=zipf is, greater than 1, the greater the hotter the hot keys")
+	cmd.Flags().StringVar(&o.keyFormat, "key-format", serde.FormatString, "The format of the key, one of "+strings.Join(serde.PrimitiveFormats, ", "))
+	cmd.Flags().StringVar(&o.headerFormat, "header-format", serde.FormatString, "The format of the header values, one of "+strings.Join(serde.PrimitiveFormats, ", "))
+	cmd.Flags().StringVar(&o.valueFormat, "value-format", serde.FormatString, "The format of the value, one of "+strings.Join(serde.Formats, ", ")+", avro takes the avro json encoding and protobuf the json mapping of the value")
+	cmd.Flags().IntVar(&o.valueSchemaID, "value-schema-id", -1, "The id of the schema to encode the value with, implies --value-format=avro unless it is protobuf")
+	cmd.Flags().StringVar(&o.valueSubject, "value-subject", "", "The subject whose latest schema encodes the value, implies --value-format=avro unless it is protobuf (default <topic>-value for avro)")
+	cmd.Flags().StringVar(&o.protoDescriptorSet, "proto-descriptor-set", "", "The FileDescriptorSet of the protobuf format, built with protoc --include_imports --descriptor_set_out=file.pb")
+	cmd.Flags().StringVar(&o.protoMessage, "proto-message", "", "The full name of the protobuf message type of the value, e.g. shop.v1.Order")
 	return cmd
 }
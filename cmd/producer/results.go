@@ -0,0 +1,93 @@
+package producer
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/log"
+	"github.com/thimico/kafka-cli/utils"
+	"go.uber.org/zap"
+	"io"
+	"sync"
+	"time"
+)
+
+// results collects the outcome of the records of --file. The successes and
+// errors of the async producer are collected concurrently.
+type results struct {
+	topic string
+	print bool
+	start time.Time
+
+	mu         sync.Mutex
+	sent       int64
+	failed     int64
+	bytes      int64
+	partitions map[int32]int64
+	// reject receives the text of every failed record
+	reject    *bufio.Writer
+	rejectErr error
+}
+
+func newResults(topic string, print bool, reject io.Writer) *results {
+	r := &results{topic: topic, print: print, start: time.Now(), partitions: map[int32]int64{}}
+	if reject != nil {
+		r.reject = bufio.NewWriter(reject)
+	}
+	return r
+}
+
+func (r *results) success(msg *sarama.ProducerMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent++
+	r.partitions[msg.Partition]++
+	if msg.Key != nil {
+		r.bytes += int64(msg.Key.Length())
+	}
+	if msg.Value != nil {
+		r.bytes += int64(msg.Value.Length())
+	}
+	if r.print {
+		utils.PrintProduceResult(utils.ProduceResult{Line: msg.Metadata.(*record).line, Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset})
+	}
+}
+
+// failure records a record which could not be parsed, encoded or sent, text
+// is its line in the input.
+func (r *results) failure(line int, text string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failed++
+	log.Warn("Record failed", zap.Int("line", line), zap.Error(err))
+	if r.print {
+		utils.PrintProduceResult(utils.ProduceResult{Line: line, Topic: r.topic, Error: err.Error()})
+	}
+	if r.reject != nil && r.rejectErr == nil {
+		_, r.rejectErr = fmt.Fprintln(r.reject, text)
+	}
+}
+
+// finish logs the counts, the records of every partition and the throughput.
+// It fails when a record failed or the reject file could not be written.
+func (r *results) finish() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	elapsed := time.Since(r.start)
+	log.Info("Produce finished", zap.String("topic", r.topic), zap.Int64("sent", r.sent), zap.Int64("failed", r.failed),
+		zap.Any("partitions", r.partitions), zap.Duration("elapsed", elapsed),
+		zap.Float64("recordsPerSec", float64(r.sent)/elapsed.Seconds()),
+		zap.Float64("mbPerSec", float64(r.bytes)/(1<<20)/elapsed.Seconds()))
+	if r.reject != nil {
+		if err := r.reject.Flush(); r.rejectErr == nil {
+			r.rejectErr = err
+		}
+		if r.rejectErr != nil {
+			return fmt.Errorf("write the reject file: %s", r.rejectErr)
+		}
+	}
+	if r.failed > 0 {
+		return fmt.Errorf("%d of %d records failed", r.failed, r.sent+r.failed)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package topic
 
 import (
+	"fmt"
 	"github.com/Shopify/sarama"
 	"github.com/thimico/kafka-cli/kafka"
 	"github.com/thimico/kafka-cli/log"
@@ -23,39 +24,12 @@ var (
 # List details for the given topics.more than one should be separated by commas
     ./kafka-cli topic --describe=singed
     result:
-        ****************************************
-		TOPIC:singed
-		DETAIL:{
- 		"Err": 0,
-		"Name": "singed",
- 		"IsInternal": false,
- 		"Partitions": [
-  		{
-   			"Err": 0,
-   			"ID": 0,
-   			"Leader": 0,
-			"Replicas": [
-   				 0
-   			],
-   			"Isr": [
-    			0
-   			],
-   			"OfflineReplicas": null
-  		},
-  		{
-   			"Err": 0,
-   			"ID": 1,
-   			"Leader": 0,
-   			"Replicas": [
-    			0
-   			],
-   			"Isr": [
-    			0
-   			],
-   			"OfflineReplicas": null
-  		}
- 		]
-		}
+        TOPIC   PARTITION   LEADER   REPLICAS   ISR
+        singed  0           0        0          0
+        singed  1           0        0          0
+
+# Print the partitions of a topic as json
+    ./kafka-cli topic --describe=singed -o json
 
 # Delete a topic.
     ./kafka-cli topic -d=singed
@@ -70,68 +44,89 @@ var (
 )
 
 type topicOptions struct {
-	bootstrapServers string
-	list             bool
-	describe         string
-	create           string
-	delete           string
-	addPartition     string
-	numPartition     int32 //创建topic时指定的partition
-	numReplica       int16 //创建topic时指定的副本数
+	client *kafka.ClientOptions
+
+	list         bool
+	describe     string
+	create       string
+	delete       string
+	addPartition string
+	numPartition int32 //创建topic时指定的partition
+	numReplica   int16 //创建topic时指定的副本数
 }
 
-func newTopicOptions() *topicOptions {
-	return &topicOptions{}
+func newTopicOptions(clientOptions *kafka.ClientOptions) *topicOptions {
+	return &topicOptions{client: clientOptions}
+}
+
+func (o *topicOptions) run(cmd *cobra.Command, args []string) error {
+	if !o.list && o.describe == "" && o.create == "" && o.delete == "" && o.addPartition == "" {
+		return cmd.Help()
+	}
+	ctx, cancel := o.client.NewContext(cmd.Context())
+	defer cancel()
+	return kafka.Wait(ctx, o.execute)
 }
 
-func (o *topicOptions) run(cmd *cobra.Command, args []string) {
-	config := sarama.NewConfig()
-	servers := strings.Split(o.bootstrapServers, ",")
-	admin, err := kafka.NewAdmin(servers, config)
-	utils.CheckErr(err)
-	defer func() {
-		utils.CheckErr(admin.Close())
-	}()
+func (o *topicOptions) execute() (err error) {
+	config, err := o.client.NewConfig()
+	if err != nil {
+		return err
+	}
+	admin, err := kafka.NewAdmin(o.client.Brokers(), config)
+	if err != nil {
+		return err
+	}
+	defer utils.Close(admin, &err)
 	if o.list {
 		topics, err := admin.ListTopics()
-		utils.CheckErr(err)
-		for k, v := range topics {
-			utils.PrintTopic(k, v)
+		if err != nil {
+			return err
 		}
+		utils.PrintTopics(topics)
 	} else if o.describe != "" {
 		topics, err := admin.DescribeTopics(strings.Split(o.describe, ","))
-		utils.CheckErr(err)
+		if err != nil {
+			return err
+		}
 		for _, v := range topics {
-			utils.PrintTopicMeta(v)
+			if v.Err != sarama.ErrNoError {
+				return fmt.Errorf("describe topic %s: %w", v.Name, v.Err)
+			}
 		}
+		utils.PrintTopicMetas(topics)
 	} else if o.create != "" {
 		err := admin.CreateTopic(o.create, &sarama.TopicDetail{NumPartitions: o.numPartition, ReplicationFactor: o.numReplica}, false)
-		utils.CheckErr(err)
+		if err != nil {
+			return err
+		}
 		log.Info("Create topic success", zap.String("topic", o.create), zap.Int32("partition num", o.numPartition), zap.Int16("replica num", o.numReplica))
 	} else if o.delete != "" {
 		err := admin.DeleteTopic(o.delete)
-		utils.CheckErr(err)
+		if err != nil {
+			return err
+		}
 		log.Info("Delete Topic success", zap.String("topic", o.delete))
 	} else if o.addPartition != "" {
 		err := admin.CreatePartitions(o.addPartition, o.numPartition, [][]int32{}, false)
-		utils.CheckErr(err)
+		if err != nil {
+			return err
+		}
 		log.Info("Add partition success", zap.String("topic", o.addPartition), zap.Int32("partition num", o.numPartition))
-	} else {
-		cmd.Help()
 	}
+	return nil
 }
 
-func NewCmdTopic() *cobra.Command {
-	o := newTopicOptions()
+func NewCmdTopic(clientOptions *kafka.ClientOptions) *cobra.Command {
+	o := newTopicOptions(clientOptions)
 
 	cmd := &cobra.Command{
 		Use:     "topic",
 		Short:   "Kafka topic operations",
 		Long:    "Topic operations, include topic create、list、delete、detail, topic partition create",
 		Example: topicExample,
-		Run:     o.run,
+		RunE:    o.run,
 	}
-	cmd.Flags().StringVarP(&o.bootstrapServers, "bootstrap-server", "b", "localhost:9092", "The Kafka server to connect to.more than one should be separated by commas")
 	cmd.Flags().BoolVarP(&o.list, "list", "l", o.list, "List all available topics.")
 	cmd.Flags().StringVar(&o.describe, "describe", o.describe, "List details for the given topics.more than one should be separated by commas")
 	cmd.Flags().StringVarP(&o.create, "create", "c", o.create, "Create a new topic.")
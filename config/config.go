@@ -0,0 +1,201 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+/*
+An example of ~/.config/kafka-cli/config.yaml
+
+current-context: dev
+contexts:
+  dev:
+    brokers: [localhost:9092]
+  prod:
+    brokers: [kafka-1.prod:9093, kafka-2.prod:9093]
+    version: 2.6.0
+    output: wide
+    tls:
+      ca-file: /etc/kafka/ca.pem
+    sasl:
+      mechanism: SCRAM-SHA-512
+      username: alice
+      password: secret
+    schema-registry:
+      url: https://registry.prod:8081
+      username: alice
+      password: secret
+*/
+
+const (
+	// EnvPrefix is the prefix of the environment variables which override flags,
+	// e.g. KAFKA_CLI_BOOTSTRAP_SERVERS overrides --bootstrap-servers.
+	EnvPrefix = "KAFKA_CLI_"
+	// EnvConfig points to an alternative config file.
+	EnvConfig = EnvPrefix + "CONFIG"
+)
+
+type TLS struct {
+	Enabled            bool   `yaml:"enabled,omitempty"`
+	CAFile             string `yaml:"ca-file,omitempty"`
+	CertFile           string `yaml:"cert-file,omitempty"`
+	KeyFile            string `yaml:"key-file,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure-skip-verify,omitempty"`
+	ServerName         string `yaml:"server-name,omitempty"`
+}
+
+type SASL struct {
+	Mechanism string `yaml:"mechanism,omitempty"`
+	Username  string `yaml:"username,omitempty"`
+	Password  string `yaml:"password,omitempty"`
+}
+
+type SchemaRegistry struct {
+	URL      string `yaml:"url,omitempty"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+}
+
+// Context is a named cluster profile.
+type Context struct {
+	Brokers []string `yaml:"brokers"`
+	Version string   `yaml:"version,omitempty"`
+	TLS     *TLS     `yaml:"tls,omitempty"`
+	SASL    *SASL    `yaml:"sasl,omitempty"`
+	Output  string   `yaml:"output,omitempty"`
+
+	SchemaRegistry *SchemaRegistry `yaml:"schema-registry,omitempty"`
+}
+
+type Config struct {
+	CurrentContext string              `yaml:"current-context,omitempty"`
+	Contexts       map[string]*Context `yaml:"contexts,omitempty"`
+
+	path string
+}
+
+// DefaultPath returns $KAFKA_CLI_CONFIG if set, otherwise ~/.config/kafka-cli/config.yaml.
+func DefaultPath() string {
+	if p := os.Getenv(EnvConfig); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".config", "kafka-cli", "config.yaml")
+	}
+	return filepath.Join(home, ".config", "kafka-cli", "config.yaml")
+}
+
+// Load reads the config file, a missing file is an empty config.
+func Load(path string) (*Config, error) {
+	if path == "" {
+		path = DefaultPath()
+	}
+	c := &Config{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("parse config %s: %s", path, err)
+	}
+	return c, nil
+}
+
+func (c *Config) Save() error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, data, 0600)
+}
+
+func (c *Config) Path() string {
+	return c.path
+}
+
+// ContextNames returns the names of all contexts in sorted order.
+func (c *Config) ContextNames() []string {
+	var names []string
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Context returns the named context, or the current context when name is empty.
+// It returns nil without error when no context is selected at all.
+func (c *Config) Context(name string) (*Context, error) {
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name == "" {
+		return nil, nil
+	}
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		return nil, fmt.Errorf("context %q not found in %s", name, c.path)
+	}
+	return ctx, nil
+}
+
+// FlagValues maps the context onto the values of the global flags it configures.
+func (ctx *Context) FlagValues() map[string]string {
+	values := map[string]string{}
+	if len(ctx.Brokers) != 0 {
+		values["bootstrap-servers"] = strings.Join(ctx.Brokers, ",")
+	}
+	if ctx.Version != "" {
+		values["kafka-version"] = ctx.Version
+	}
+	setString(values, "output", ctx.Output)
+	if t := ctx.TLS; t != nil {
+		setBool(values, "tls", t.Enabled)
+		setString(values, "tls-ca-file", t.CAFile)
+		setString(values, "tls-cert-file", t.CertFile)
+		setString(values, "tls-key-file", t.KeyFile)
+		setBool(values, "tls-insecure-skip-verify", t.InsecureSkipVerify)
+		setString(values, "tls-server-name", t.ServerName)
+	}
+	if s := ctx.SASL; s != nil {
+		setString(values, "sasl-mechanism", s.Mechanism)
+		setString(values, "sasl-username", s.Username)
+		setString(values, "sasl-password", s.Password)
+	}
+	if r := ctx.SchemaRegistry; r != nil {
+		setString(values, "schema-registry-url", r.URL)
+		setString(values, "schema-registry-username", r.Username)
+		setString(values, "schema-registry-password", r.Password)
+	}
+	return values
+}
+
+// EnvName returns the environment variable which overrides the given flag.
+func EnvName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(flag, "-", "_", -1))
+}
+
+func setString(values map[string]string, key, value string) {
+	if value != "" {
+		values[key] = value
+	}
+}
+
+func setBool(values map[string]string, key string, value bool) {
+	if value {
+		values[key] = "true"
+	}
+}
@@ -0,0 +1,301 @@
+// Package dump writes and reads the records of a topic dump, either as one
+// json object per line or in a compact binary format. Both keep the
+// partition, offset, timestamp, key, value and headers of every record and
+// are binary safe, null keys and values stay null.
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// The formats of a dump file.
+const (
+	FormatNDJSON = "ndjson"
+	FormatBinary = "binary"
+)
+
+// Formats lists the formats of a dump file.
+var Formats = []string{FormatNDJSON, FormatBinary}
+
+// binaryMagic starts every binary dump, it can not start an ndjson line.
+var binaryMagic = []byte("KCDUMP\x00\x01")
+
+// Record is a dumped message, nil keys and values are null.
+type Record struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Timestamp time.Time
+	Key       []byte
+	Value     []byte
+	Headers   []Header
+}
+
+type Header struct {
+	Key   []byte
+	Value []byte
+}
+
+// Writer writes the records of a dump.
+type Writer interface {
+	Write(r *Record) error
+	// Flush writes the buffered records.
+	Flush() error
+}
+
+// Reader reads the records of a dump, Read returns io.EOF at the end.
+type Reader interface {
+	Read() (*Record, error)
+}
+
+// CheckFormat returns an error if format is not a dump format.
+func CheckFormat(format string) error {
+	if format != FormatNDJSON && format != FormatBinary {
+		return fmt.Errorf("unknown dump format %q, should be one of %s", format, strings.Join(Formats, ", "))
+	}
+	return nil
+}
+
+// NewWriter returns a writer of format to w.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	if err := CheckFormat(format); err != nil {
+		return nil, err
+	}
+	bw := bufio.NewWriter(w)
+	if format == FormatBinary {
+		if _, err := bw.Write(binaryMagic); err != nil {
+			return nil, err
+		}
+		return &binaryWriter{w: bw}, nil
+	}
+	return &ndjsonWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+}
+
+// NewReader returns a reader of r, the format is detected from its first bytes.
+func NewReader(r io.Reader) (Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(binaryMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(head, binaryMagic) {
+		br.Discard(len(binaryMagic))
+		return &binaryReader{r: br}, nil
+	}
+	return &ndjsonReader{r: br}, nil
+}
+
+// ndjsonRecord is the json object of a record, bytes are base64 and the
+// timestamp is omitted when the message has none.
+type ndjsonRecord struct {
+	Topic     string         `json:"topic"`
+	Partition int32          `json:"partition"`
+	Offset    int64          `json:"offset"`
+	Timestamp *time.Time     `json:"timestamp,omitempty"`
+	Key       []byte         `json:"key"`
+	Value     []byte         `json:"value"`
+	Headers   []ndjsonHeader `json:"headers,omitempty"`
+}
+
+type ndjsonHeader struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(r *Record) error {
+	rec := ndjsonRecord{Topic: r.Topic, Partition: r.Partition, Offset: r.Offset, Key: r.Key, Value: r.Value}
+	if !r.Timestamp.IsZero() {
+		ts := r.Timestamp.UTC()
+		rec.Timestamp = &ts
+	}
+	for _, h := range r.Headers {
+		rec.Headers = append(rec.Headers, ndjsonHeader{Key: h.Key, Value: h.Value})
+	}
+	return w.enc.Encode(&rec)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.w.Flush()
+}
+
+type ndjsonReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+func (r *ndjsonReader) Read() (*Record, error) {
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		}
+		r.line++
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var rec ndjsonRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			var corrupt base64.CorruptInputError
+			if errors.As(err, &corrupt) {
+				err = fmt.Errorf("keys, values and headers should be base64: %s", err)
+			}
+			return nil, fmt.Errorf("line %d: %s", r.line, err)
+		}
+		record := &Record{Topic: rec.Topic, Partition: rec.Partition, Offset: rec.Offset, Key: rec.Key, Value: rec.Value}
+		if rec.Timestamp != nil {
+			record.Timestamp = *rec.Timestamp
+		}
+		for _, h := range rec.Headers {
+			record.Headers = append(record.Headers, Header{Key: h.Key, Value: h.Value})
+		}
+		return record, nil
+	}
+}
+
+// binaryWriter writes every record as varints and length prefixed bytes:
+// topic, partition, offset, timestamp in unix milliseconds or -1, key,
+// value, the number of headers and their keys and values. The length of
+// null bytes is -1.
+type binaryWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (w *binaryWriter) Write(r *Record) error {
+	w.bytes([]byte(r.Topic))
+	w.varint(int64(r.Partition))
+	w.varint(r.Offset)
+	ts := int64(-1)
+	if !r.Timestamp.IsZero() {
+		ts = r.Timestamp.UnixNano() / int64(time.Millisecond)
+	}
+	w.varint(ts)
+	w.bytes(r.Key)
+	w.bytes(r.Value)
+	w.varint(int64(len(r.Headers)))
+	for _, h := range r.Headers {
+		w.bytes(h.Key)
+		w.bytes(h.Value)
+	}
+	// bufio keeps the first error and returns it again
+	_, err := w.w.Write(nil)
+	return err
+}
+
+func (w *binaryWriter) varint(v int64) {
+	w.w.Write(w.buf[:binary.PutVarint(w.buf[:], v)])
+}
+
+func (w *binaryWriter) bytes(b []byte) {
+	if b == nil {
+		w.varint(-1)
+		return
+	}
+	w.varint(int64(len(b)))
+	w.w.Write(b)
+}
+
+func (w *binaryWriter) Flush() error {
+	return w.w.Flush()
+}
+
+type binaryReader struct {
+	r     *bufio.Reader
+	count int
+}
+
+var errTruncated = errors.New("truncated record")
+
+func (r *binaryReader) Read() (*Record, error) {
+	topic, err := r.bytes()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	rec := &Record{Topic: string(topic)}
+	var partition, ts, headers int64
+	if err == nil {
+		partition, err = r.varint()
+	}
+	if err == nil {
+		rec.Offset, err = r.varint()
+	}
+	if err == nil {
+		ts, err = r.varint()
+	}
+	if err == nil {
+		rec.Key, err = r.bytes()
+	}
+	if err == nil {
+		rec.Value, err = r.bytes()
+	}
+	if err == nil {
+		headers, err = r.varint()
+	}
+	if err == nil && headers < 0 {
+		err = errors.New("invalid header count")
+	}
+	for i := int64(0); err == nil && i < headers; i++ {
+		var h Header
+		if h.Key, err = r.bytes(); err == nil {
+			h.Value, err = r.bytes()
+		}
+		rec.Headers = append(rec.Headers, h)
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = errTruncated
+		}
+		return nil, fmt.Errorf("record %d: %s", r.count+1, err)
+	}
+	r.count++
+	rec.Partition = int32(partition)
+	if ts >= 0 {
+		rec.Timestamp = time.Unix(0, ts*int64(time.Millisecond)).UTC()
+	}
+	return rec, nil
+}
+
+func (r *binaryReader) varint() (int64, error) {
+	v, err := binary.ReadVarint(r.r)
+	if err == io.ErrUnexpectedEOF {
+		err = errTruncated
+	}
+	return v, err
+}
+
+func (r *binaryReader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil || n == -1 {
+		return nil, err
+	}
+	if n < -1 || n > math.MaxInt32 {
+		return nil, errors.New("invalid length")
+	}
+	if n == 0 {
+		return []byte{}, nil
+	}
+	// the length is not trusted, the buffer grows only with the bytes read
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r.r, n); err != nil {
+		if err == io.EOF {
+			err = errTruncated
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
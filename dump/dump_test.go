@@ -0,0 +1,158 @@
+package dump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRecords() []*Record {
+	ts := time.Date(2024, 5, 1, 12, 30, 0, 123000000, time.UTC)
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	return []*Record{
+		{Topic: "orders", Partition: 0, Offset: 0, Timestamp: ts, Key: []byte("k"), Value: []byte(`{"id":1}`)},
+		{Topic: "orders", Partition: 1, Offset: 7, Timestamp: ts, Key: nil, Value: []byte{}},
+		{Topic: "orders", Partition: 1, Offset: 8, Timestamp: ts, Key: []byte{}, Value: nil},
+		{Topic: "orders", Partition: 2, Offset: 1 << 40, Timestamp: ts, Key: all, Value: []byte("\x00\xff\n\r")},
+		{Topic: "orders", Partition: 2, Offset: 9, Timestamp: ts, Value: []byte("v"), Headers: []Header{
+			{Key: []byte("source"), Value: []byte("web")},
+			{Key: []byte("trace"), Value: nil},
+			{Key: []byte{}, Value: []byte{0, 1}},
+		}},
+		// messages before kafka 0.10 have no timestamp
+		{Topic: "orders", Partition: 3, Offset: 2, Value: []byte("old")},
+	}
+}
+
+func writeRecords(t *testing.T, format string, records []*Record) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readRecords(data []byte) ([]*Record, error) {
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var records []*Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			want := testRecords()
+			data := writeRecords(t, format, want)
+			if got := bytes.HasPrefix(data, binaryMagic); got != (format == FormatBinary) {
+				t.Errorf("starts with the binary magic = %v", got)
+			}
+			got, err := readRecords(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("read %d records, want %d", len(got), len(want))
+			}
+			for i := range want {
+				// reflect.DeepEqual tells nil and empty slices apart
+				if !reflect.DeepEqual(got[i], want[i]) {
+					t.Errorf("record %d = %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadEmpty(t *testing.T) {
+	for _, format := range Formats {
+		got, err := readRecords(writeRecords(t, format, nil))
+		if err != nil || len(got) != 0 {
+			t.Errorf("%s: read %v, %v from an empty dump", format, got, err)
+		}
+	}
+}
+
+func TestReadNDJSONBlankLines(t *testing.T) {
+	got, err := readRecords([]byte("\n{\"topic\":\"t\",\"offset\":3,\"key\":null,\"value\":\"dg==\"}\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Record{{Topic: "t", Offset: 3, Value: []byte("v")}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read %+v, want %+v", got, want)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	binaryDump := writeRecords(t, FormatBinary, testRecords()[:2])
+	varint := func(v int64) []byte {
+		buf := make([]byte, binary.MaxVarintLen64)
+		return buf[:binary.PutVarint(buf, v)]
+	}
+	tests := []struct {
+		name string
+		data []byte
+		// records is how many records are read before the error
+		records int
+		err     string
+	}{
+		{"binary truncated", binaryDump[:len(binaryDump)-1], 1, "record 2: truncated record"},
+		{"binary truncated varint", append(append([]byte{}, binaryMagic...), 0x80), 0, "record 1: truncated record"},
+		// a corrupt length does not allocate before the bytes are there
+		{"binary huge length", append(append(append([]byte{}, binaryMagic...), varint(math.MaxInt32)...), 'x'), 0, "record 1: truncated record"},
+		{"binary too long", append(append([]byte{}, binaryMagic...), varint(math.MaxInt32+1)...), 0, "record 1: invalid length"},
+		{"binary negative length", append(append([]byte{}, binaryMagic...), varint(-2)...), 0, "record 1: invalid length"},
+		{"ndjson invalid json", []byte("{\"topic\":\"t\"}\n{\"topic\":\n"), 1, "line 2:"},
+		{"ndjson not base64", []byte(`{"topic":"t","value":"not base64!"}`), 0, "line 1: keys, values and headers should be base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readRecords(tt.data)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+			if len(got) != tt.records {
+				t.Errorf("read %d records before the error, want %d", len(got), tt.records)
+			}
+		})
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	if err := CheckFormat("csv"); err == nil {
+		t.Error("csv is not a dump format")
+	}
+	if _, err := NewWriter(&bytes.Buffer{}, "csv"); err == nil {
+		t.Error("NewWriter accepted csv")
+	}
+}
@@ -0,0 +1,403 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/thimico/kafka-cli/serde"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Filter selects consumed messages by one or more expressions, a message
+// matches when it matches all of them. A nil Filter matches every message.
+type Filter struct {
+	exprs   []node
+	scanned int64
+	matched int64
+}
+
+// New parses the filter expressions, it returns nil without expressions.
+func New(exprs []string) (*Filter, error) {
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	f := &Filter{}
+	now := time.Now()
+	for _, s := range exprs {
+		n, err := parse(s, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %s", s, err)
+		}
+		f.exprs = append(f.exprs, n)
+	}
+	return f, nil
+}
+
+// Match reports whether the decoded message matches the filter. It is safe
+// for concurrent use.
+func (f *Filter) Match(msg *serde.Message) bool {
+	if f == nil {
+		return true
+	}
+	atomic.AddInt64(&f.scanned, 1)
+	m := &message{msg: msg}
+	for _, n := range f.exprs {
+		if !truthy(n.eval(m)) {
+			return false
+		}
+	}
+	atomic.AddInt64(&f.matched, 1)
+	return true
+}
+
+// Counts returns the number of scanned and matched messages.
+func (f *Filter) Counts() (scanned, matched int64) {
+	if f == nil {
+		return 0, 0
+	}
+	return atomic.LoadInt64(&f.scanned), atomic.LoadInt64(&f.matched)
+}
+
+// message decodes string keys and values as json at most once.
+type message struct {
+	msg              *serde.Message
+	key, value       interface{}
+	keyOK, valOK     bool
+	keyDone, valDone bool
+}
+
+func (m *message) json(data string, v *interface{}, ok, done *bool) (interface{}, bool) {
+	if !*done {
+		*done = true
+		*ok = json.Unmarshal([]byte(data), v) == nil
+	}
+	return *v, *ok
+}
+
+type node interface {
+	eval(m *message) interface{}
+}
+
+type literal struct {
+	v interface{}
+}
+
+func (l literal) eval(*message) interface{} {
+	return l.v
+}
+
+type fieldNode struct {
+	root string
+	path []interface{}
+}
+
+func (f fieldNode) eval(m *message) interface{} {
+	msg := m.msg.Raw
+	switch f.root {
+	case "topic":
+		return msg.Topic
+	case "partition":
+		return float64(msg.Partition)
+	case "offset":
+		return float64(msg.Offset)
+	case "timestamp":
+		return msg.Timestamp
+	case "headers":
+		name, _ := f.path[0].(string)
+		for _, h := range m.msg.Headers {
+			if h.Key == name {
+				return lookup(h.Value, f.path[1:], false)
+			}
+		}
+		return nil
+	case "key", "value":
+		v := m.msg.Value
+		if f.root == "key" {
+			v = m.msg.Key
+		}
+		data, isString := v.(string)
+		if !isString {
+			// values of schema based formats are decoded already
+			return lookup(v, f.path, true)
+		}
+		if len(f.path) == 0 {
+			return v
+		}
+		var ok bool
+		if f.root == "key" {
+			v, ok = m.json(data, &m.key, &m.keyOK, &m.keyDone)
+		} else {
+			v, ok = m.json(data, &m.value, &m.valOK, &m.valDone)
+		}
+		if !ok {
+			return nil
+		}
+		return lookup(v, f.path, false)
+	}
+	return nil
+}
+
+// lookup walks a decoded document along path, integers of schema based
+// formats are returned as float64 like the numbers of json. With unions the
+// avro unions on the way are unwrapped.
+func lookup(v interface{}, path []interface{}, unions bool) interface{} {
+	for _, p := range path {
+		if unions {
+			v = unwrapUnion(v, p)
+		}
+		switch p := p.(type) {
+		case string:
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			v = obj[p]
+		case int:
+			arr, ok := v.([]interface{})
+			if !ok || p < 0 || p >= len(arr) {
+				return nil
+			}
+			v = arr[p]
+		}
+	}
+	if unions {
+		v = unwrapUnion(v, nil)
+	}
+	if i, ok := v.(int64); ok {
+		return float64(i)
+	}
+	return v
+}
+
+// avroUnnamed are the branch names of avro unions of unnamed types.
+var avroUnnamed = map[string]bool{
+	"boolean": true, "int": true, "long": true, "float": true, "double": true, "bytes": true, "string": true,
+	"array": true, "map": true,
+}
+
+// unwrapUnion returns the value of an avro union, which the avro json
+// encoding wraps in an object keyed by its branch, e.g. {"string":"x"}, so
+// value.name == "x" works as well as value.name.string == "x". A record branch is keyed by its full name and
+// unwrapped when it has the next field of the path, an enum or fixed branch
+// when the name has a namespace, null is not wrapped.
+func unwrapUnion(v interface{}, next interface{}) interface{} {
+	obj, ok := v.(map[string]interface{})
+	if !ok || len(obj) != 1 {
+		return v
+	}
+	for branch, x := range obj {
+		if branch == next {
+			// the path names the branch, like value.name.string
+			return v
+		}
+		if avroUnnamed[branch] {
+			return x
+		}
+		if record, ok := x.(map[string]interface{}); ok {
+			if name, ok := next.(string); ok && name != branch {
+				if _, ok := record[name]; ok {
+					return x
+				}
+			}
+		} else if next == nil && strings.Contains(branch, ".") {
+			return x
+		}
+	}
+	return v
+}
+
+type notNode struct {
+	x node
+}
+
+func (n notNode) eval(m *message) interface{} {
+	return !truthy(n.x.eval(m))
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n andNode) eval(m *message) interface{} {
+	return truthy(n.left.eval(m)) && truthy(n.right.eval(m))
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n orNode) eval(m *message) interface{} {
+	return truthy(n.left.eval(m)) || truthy(n.right.eval(m))
+}
+
+type inNode struct {
+	x    node
+	list []node
+}
+
+func (n inNode) eval(m *message) interface{} {
+	v := n.x.eval(m)
+	for _, item := range n.list {
+		if c, ok := compare(v, item.eval(m)); ok && c == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type matchNode struct {
+	x      node
+	re     *regexp.Regexp
+	negate bool
+}
+
+func (n matchNode) eval(m *message) interface{} {
+	v := n.x.eval(m)
+	if v == nil {
+		return n.negate
+	}
+	return n.re.MatchString(toString(v)) != n.negate
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(m *message) interface{} {
+	l, r := n.left.eval(m), n.right.eval(m)
+	if l == nil || r == nil {
+		switch n.op {
+		case "==":
+			return l == nil && r == nil
+		case "!=":
+			return (l == nil) != (r == nil)
+		}
+		return false
+	}
+	c, ok := compare(l, r)
+	if !ok {
+		return n.op == "!="
+	}
+	switch n.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// compare orders two values, strings holding numbers compare as numbers.
+// It returns false when the values can not be compared.
+func compare(l, r interface{}) (int, bool) {
+	switch l := l.(type) {
+	case float64:
+		if f, ok := toFloat(r); ok {
+			return compareFloat(l, f), true
+		}
+	case string:
+		switch r := r.(type) {
+		case string:
+			return compareString(l, r), true
+		case float64:
+			if f, ok := toFloat(l); ok {
+				return compareFloat(f, r), true
+			}
+		}
+	case bool:
+		if r, ok := r.(bool); ok {
+			if l == r {
+				return 0, true
+			}
+			if !l {
+				return -1, true
+			}
+			return 1, true
+		}
+	case time.Time:
+		if r, ok := r.(time.Time); ok {
+			switch {
+			case l.Before(r):
+				return -1, true
+			case l.After(r):
+				return 1, true
+			}
+			return 0, true
+		}
+	case nil:
+		return 0, r == nil
+	}
+	return 0, false
+}
+
+func compareFloat(l, r float64) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+func compareString(l, r string) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
+
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case float64:
+		return v != 0
+	}
+	return true
+}
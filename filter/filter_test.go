@@ -0,0 +1,221 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/serde"
+)
+
+var testTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// testMessage is a json order with a string key and two headers.
+func testMessage() *serde.Message {
+	return &serde.Message{
+		Raw: &sarama.ConsumerMessage{Topic: "orders", Partition: 3, Offset: 42, Timestamp: testTime},
+		Key: "user-17",
+		Value: `{"id":"o-1","status":"FAILED","amount":150.5,"count":"7","paid":false,"note":null,
+			"items":[{"sku":"A-1","qty":2},{"sku":"B-2","qty":1}],"customer":{"name":"Ada","tags":["vip"]}}`,
+		Headers: []serde.Header{{Key: "source", Value: "web"}, {Key: "x-id", Value: "abc"}},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		// fields of the message
+		{`topic == "orders"`, true},
+		{`partition == 3 && offset >= 42`, true},
+		{`offset < 42`, false},
+		{`key == "user-17"`, true},
+		{`headers.source == "web"`, true},
+		{`headers["x-id"] == "abc"`, true},
+		{`headers.missing == null`, true},
+
+		// json fields of a string value
+		{`value.status == "FAILED"`, true},
+		{`value.amount > 100`, true},
+		{`value.amount <= 150.5 && value.amount >= 150.5`, true},
+		{`value.count == 7`, true},
+		{`value.count > "10"`, true},
+		{`value.paid == false`, true},
+		{`value.paid`, false},
+		{`value.items[1].sku == "B-2"`, true},
+		{`value.items[0]["qty"] == 2`, true},
+		{`value.customer.tags[0] == "vip"`, true},
+		{`value.customer == value.customer`, false},
+
+		// missing fields and nulls
+		{`value.missing == null`, true},
+		{`value.note == null`, true},
+		{`value.missing != null`, false},
+		{`value.missing > 1`, false},
+		{`value.missing < 1`, false},
+		{`value.items[5].sku == null`, true},
+		{`value.status.deeper == null`, true},
+		{`key.field == null`, true},
+		{`value.status != 1`, true},
+
+		// precedence: ! binds tighter than &&, && tighter than ||
+		{`topic == "x" && topic == "y" || topic == "orders"`, true},
+		{`topic == "orders" || topic == "x" && topic == "y"`, true},
+		{`(topic == "orders" || topic == "x") && topic == "y"`, false},
+		{`!topic == "orders"`, false},
+		{`!(topic == "x") && !value.paid`, true},
+		{`!!value.status`, true},
+
+		// in
+		{`partition in (0, 3)`, true},
+		{`partition in (0, 1)`, false},
+		{`value.status in ("NEW", "FAILED")`, true},
+		{`value.count in (7)`, true},
+		{`value.missing in (null)`, true},
+
+		// regular expressions
+		{`key =~ "^user-"`, true},
+		{`key =~ '^user-\d+$'`, true},
+		{`key !~ "^user-"`, false},
+		{`value.amount =~ "^150\\.5$"`, true},
+		{`value.items =~ "B-2"`, true},
+		{`value.missing =~ "."`, false},
+		{`value.missing !~ "."`, true},
+
+		// timestamps compare with times
+		{`timestamp == "2024-05-01T12:00:00Z"`, true},
+		{`timestamp < "2024-05-01T12:00:01Z"`, true},
+		{`"2024-05-01T11:00:00Z" < timestamp`, true},
+		{`timestamp in ("2024-05-01T12:00:00Z")`, true},
+		{`timestamp >= "-15m"`, false},
+		{`timestamp < "-15m"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := New([]string{tt.expr})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Match(testMessage()); got != tt.match {
+				t.Errorf("Match = %v, want %v", got, tt.match)
+			}
+		})
+	}
+}
+
+func TestMatchDecodedValues(t *testing.T) {
+	// values of the schema based formats are decoded already, integers are int64
+	msg := &serde.Message{
+		Raw:   &sarama.ConsumerMessage{Topic: "orders"},
+		Key:   int64(100),
+		Value: map[string]interface{}{"id": int64(7), "lines": []interface{}{map[string]interface{}{"sku": "A-1"}}},
+	}
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{`key > 99`, true},
+		{`key == "100"`, true},
+		{`value.id == 7`, true},
+		{`value.lines[0].sku == "A-1"`, true},
+		{`value.lines[1].sku == null`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := New([]string{tt.expr})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Match(msg); got != tt.match {
+				t.Errorf("Match = %v, want %v", got, tt.match)
+			}
+		})
+	}
+}
+
+func TestMatchAvroUnions(t *testing.T) {
+	// the avro json encoding wraps the values of unions in their branch
+	msg := &serde.Message{
+		Raw: &sarama.ConsumerMessage{Topic: "users"},
+		Key: map[string]interface{}{"long": int64(7)},
+		Value: map[string]interface{}{
+			"name":    map[string]interface{}{"string": "Ada"},
+			"email":   nil,
+			"tags":    map[string]interface{}{"array": []interface{}{"vip"}},
+			"address": map[string]interface{}{"com.shop.Address": map[string]interface{}{"city": "Lisbon"}},
+			"status":  map[string]interface{}{"com.shop.Status": "ACTIVE"},
+			"string":  "a field named like a branch",
+		},
+	}
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{`key == 7`, true},
+		{`value.name == "Ada"`, true},
+		{`value.name =~ "^A"`, true},
+		{`value.name.string == "Ada"`, true},
+		{`value.email == null`, true},
+		{`value.tags[0] == "vip"`, true},
+		{`value.address.city == "Lisbon"`, true},
+		{`value.address["com.shop.Address"].city == "Lisbon"`, true},
+		{`value.address.zip == null`, true},
+		{`value.status == "ACTIVE"`, true},
+		{`value.string == "a field named like a branch"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := New([]string{tt.expr})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Match(msg); got != tt.match {
+				t.Errorf("Match = %v, want %v", got, tt.match)
+			}
+		})
+	}
+
+	// json text is not avro, objects with a single field stay objects
+	json := testMessage()
+	json.Value = `{"name":{"string":"Ada"}}`
+	f, err := New([]string{`value.name.string == "Ada"`, `value.name != "Ada"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Match(json) {
+		t.Error("unwrapped a json object")
+	}
+}
+
+func TestMatchNotJSON(t *testing.T) {
+	msg := testMessage()
+	msg.Value = "plain text"
+	f, err := New([]string{`value.status == null`, `value == "plain text"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Match(msg) {
+		t.Error("a value which is not json should have null fields")
+	}
+}
+
+func TestCounts(t *testing.T) {
+	f, err := New([]string{`partition == 3`, `value.status == "FAILED"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := testMessage()
+	other.Raw.Partition = 1
+	for _, msg := range []*serde.Message{testMessage(), other, testMessage()} {
+		f.Match(msg)
+	}
+	if scanned, matched := f.Counts(); scanned != 3 || matched != 2 {
+		t.Errorf("Counts = %d, %d, want 3, 2", scanned, matched)
+	}
+
+	var none *Filter
+	if !none.Match(testMessage()) {
+		t.Error("a nil filter should match every message")
+	}
+}
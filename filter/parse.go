@@ -0,0 +1,341 @@
+package filter
+
+import (
+	"fmt"
+	"github.com/thimico/kafka-cli/kafka"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/*
+The filter grammar:
+
+	expr       = and { "||" and }
+	and        = unary { "&&" unary }
+	unary      = "!" unary | "(" expr ")" | comparison
+	comparison = operand [ op operand | "in" "(" operand { "," operand } ")" ]
+	op         = "==" | "!=" | "<" | "<=" | ">" | ">=" | "=~" | "!~"
+	operand    = field | string | number | "true" | "false" | "null"
+	field      = root { "." name | "[" ( string | number ) "]" }
+	root       = "topic" | "partition" | "offset" | "timestamp" | "key" | "value" | "headers"
+*/
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(s) && s[end] != s[i] {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			text := s[i : end+1]
+			if c == '\'' {
+				text = strconv.Quote(strings.Replace(s[i+1:end], `\'`, `'`, -1))
+			}
+			unquoted, err := strconv.Unquote(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at %d: %s", i, err)
+			}
+			tokens = append(tokens, token{kind: tokString, text: unquoted, pos: i})
+			i = end + 1
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1]))):
+			end := i + 1
+			for end < len(s) && (unicode.IsDigit(rune(s[end])) || s[end] == '.' || s[end] == 'e' || s[end] == 'E') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: s[i:end], pos: i})
+			i = end
+		case unicode.IsLetter(c) || c == '_':
+			end := i + 1
+			for end < len(s) && (unicode.IsLetter(rune(s[end])) || unicode.IsDigit(rune(s[end])) || s[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: s[i:end], pos: i})
+			i = end
+		default:
+			op := ""
+			for _, o := range []string{"==", "!=", "<=", ">=", "=~", "!~", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ".", ","} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(s)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	now    time.Time
+}
+
+// parse parses a single filter expression.
+func parse(s string, now time.Time) (node, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, now: now}
+	n, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("expected %q at %d", op, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) expr() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.accept("!") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x}, nil
+	}
+	if p.accept("(") {
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind == tokIdent && t.text == "in" {
+		p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		in := inNode{x: left}
+		for {
+			item, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			if item, err = p.coerce(left, item); err != nil {
+				return nil, err
+			}
+			in.list = append(in.list, item)
+			if !p.accept(",") {
+				break
+			}
+		}
+		return in, p.expect(")")
+	}
+	if t.kind != tokOp {
+		return left, nil
+	}
+	switch t.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if right, err = p.coerce(left, right); err != nil {
+			return nil, err
+		}
+		if left, err = p.coerce(right, left); err != nil {
+			return nil, err
+		}
+		return compareNode{op: t.text, left: left, right: right}, nil
+	case "=~", "!~":
+		p.next()
+		pattern := p.next()
+		if pattern.kind != tokString {
+			return nil, fmt.Errorf("%s expects a string pattern at %d", t.text, pattern.pos)
+		}
+		re, err := regexp.Compile(pattern.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern at %d: %s", pattern.pos, err)
+		}
+		return matchNode{x: left, re: re, negate: t.text == "!~"}, nil
+	}
+	return left, nil
+}
+
+// coerce turns a string compared with the timestamp into a time, so
+// timestamp >= "-15m" and timestamp < "2021-02-04T09:00:00Z" work.
+func (p *parser) coerce(other, n node) (node, error) {
+	f, ok := other.(fieldNode)
+	lit, isLit := n.(literal)
+	s, isString := lit.v.(string)
+	if !ok || f.root != "timestamp" || len(f.path) != 0 || !isLit || !isString {
+		return n, nil
+	}
+	t, err := kafka.ParseTime(s, p.now)
+	if err != nil {
+		return nil, err
+	}
+	return literal{t}, nil
+}
+
+var roots = map[string]bool{
+	"topic": true, "partition": true, "offset": true, "timestamp": true, "key": true, "value": true, "headers": true,
+}
+
+func (p *parser) operand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return literal{t.text}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		return literal{f}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		case "null":
+			return literal{nil}, nil
+		}
+		if !roots[t.text] {
+			return nil, fmt.Errorf("unknown field %q at %d, should start with topic, partition, offset, timestamp, key, value or headers", t.text, t.pos)
+		}
+		f := fieldNode{root: t.text}
+		for {
+			if p.accept(".") {
+				name := p.next()
+				if name.kind != tokIdent {
+					return nil, fmt.Errorf("expected a field name at %d", name.pos)
+				}
+				f.path = append(f.path, name.text)
+			} else if p.accept("[") {
+				index := p.next()
+				switch index.kind {
+				case tokString:
+					f.path = append(f.path, index.text)
+				case tokNumber:
+					i, err := strconv.Atoi(index.text)
+					if err != nil {
+						return nil, fmt.Errorf("invalid index %q at %d", index.text, index.pos)
+					}
+					f.path = append(f.path, i)
+				default:
+					return nil, fmt.Errorf("expected a string or number index at %d", index.pos)
+				}
+				if err := p.expect("]"); err != nil {
+					return nil, err
+				}
+			} else {
+				break
+			}
+		}
+		if f.root == "headers" && len(f.path) == 0 {
+			return nil, fmt.Errorf("headers needs a header name at %d, like headers.source or headers[\"x-id\"]", t.pos)
+		}
+		return f, nil
+	}
+	if t.kind == tokEOF {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
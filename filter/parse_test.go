@@ -0,0 +1,53 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{`key == "a`, "unterminated string at 7"},
+		{`key == "a\x"`, "invalid string at 7"},
+		{`key # 1`, `unexpected '#' at 4`},
+		{`key ==`, "unexpected end of filter"},
+		{`key == 1 2`, `unexpected "2" at 9`},
+		{`name == 1`, `unknown field "name" at 0`},
+		{`(key == 1`, `expected ")" at 9`},
+		{`key in 1`, `expected "(" at 7`},
+		{`key in (1, 2`, `expected ")" at 12`},
+		{`key in (1,)`, `unexpected ")" at 10`},
+		{`key =~ 1`, "=~ expects a string pattern at 7"},
+		{`key !~ "("`, "invalid pattern at 7"},
+		{`value. == 1`, "expected a field name at 7"},
+		{`value[true] == 1`, "expected a string or number index at 6"},
+		{`value[1.5] == 1`, `invalid index "1.5" at 6`},
+		{`value[0 == 1`, `expected "]" at 8`},
+		{`headers == "a"`, "headers needs a header name at 0"},
+		{`offset > 1e`, `invalid number "1e" at 9`},
+		{`timestamp > "yesterday"`, "yesterday"},
+		{`&& key`, `unexpected "&&" at 0`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := parse(tt.expr, time.Now())
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseNewPrefixesExpression(t *testing.T) {
+	_, err := New([]string{`key == 1`, `key ==`})
+	if want := `invalid filter "key ==": unexpected end of filter`; err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+	if f, err := New(nil); f != nil || err != nil {
+		t.Errorf("New(nil) = %v, %v, want nil", f, err)
+	}
+}
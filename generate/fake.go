@@ -0,0 +1,60 @@
+package generate
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+var (
+	firstNames = []string{
+		"Ada", "Alan", "Alice", "Amara", "Ana", "Ben", "Carlos", "Chen", "Chloe", "David",
+		"Elena", "Emma", "Farah", "Felix", "Grace", "Hana", "Hugo", "Ines", "Ivan", "James",
+		"Julia", "Kenji", "Lara", "Leo", "Lina", "Lucas", "Maria", "Mateo", "Mia", "Noah",
+		"Nora", "Olivia", "Omar", "Paula", "Priya", "Rafael", "Rosa", "Sam", "Sofia", "Tariq",
+		"Thiago", "Uma", "Victor", "Wei", "Yara", "Yusuf", "Zoe",
+	}
+	lastNames = []string{
+		"Almeida", "Anderson", "Becker", "Brown", "Costa", "Dubois", "Garcia", "Gonzalez", "Hansen", "Ibrahim",
+		"Ito", "Jensen", "Kim", "Kowalski", "Lee", "Lopez", "Martin", "Meyer", "Moreau", "Müller",
+		"Nakamura", "Nguyen", "Novak", "Oliveira", "Park", "Patel", "Petrov", "Rossi", "Santos", "Schmidt",
+		"Silva", "Singh", "Smith", "Souza", "Tanaka", "Taylor", "Walker", "Wang", "Williams", "Yilmaz",
+	}
+	emailDomains = []string{"example.com", "example.org", "example.net", "mail.test", "inbox.test"}
+	streets      = []string{
+		"Main Street", "Oak Avenue", "Maple Road", "Cedar Lane", "Park Street", "Lake View", "Hill Road",
+		"River Street", "Station Road", "Church Lane", "Market Square", "Elm Street", "Sunset Boulevard",
+	}
+	cities = []string{
+		"Amsterdam", "Austin", "Barcelona", "Berlin", "Bogota", "Curitiba", "Dublin", "Lisbon", "Lyon", "Madrid",
+		"Melbourne", "Milan", "Montreal", "Osaka", "Porto", "Recife", "Seattle", "Seoul", "Toronto", "Zurich",
+	}
+	countries = []string{
+		"Australia", "Brazil", "Canada", "Colombia", "France", "Germany", "Ireland", "Italy", "Japan",
+		"Netherlands", "Portugal", "South Korea", "Spain", "Switzerland", "United States",
+	}
+)
+
+// fake returns the fake data template functions, drawn from rnd.
+func fake(rnd *rand.Rand) map[string]interface{} {
+	pick := func(list []string) string {
+		return list[rnd.Intn(len(list))]
+	}
+	return map[string]interface{}{
+		"firstName": func() string { return pick(firstNames) },
+		"lastName":  func() string { return pick(lastNames) },
+		"name":      func() string { return pick(firstNames) + " " + pick(lastNames) },
+		"email": func() string {
+			return fmt.Sprintf("%s.%s%d@%s", strings.ToLower(pick(firstNames)), strings.ToLower(pick(lastNames)),
+				rnd.Intn(100), pick(emailDomains))
+		},
+		"phone":   func() string { return fmt.Sprintf("+1-555-%03d-%04d", rnd.Intn(1000), rnd.Intn(10000)) },
+		"street":  func() string { return fmt.Sprintf("%d %s", 1+rnd.Intn(999), pick(streets)) },
+		"city":    func() string { return pick(cities) },
+		"country": func() string { return pick(countries) },
+		"zip":     func() string { return fmt.Sprintf("%05d", rnd.Intn(100000)) },
+		"address": func() string {
+			return fmt.Sprintf("%d %s, %s %05d, %s", 1+rnd.Intn(999), pick(streets), pick(cities), rnd.Intn(100000), pick(countries))
+		},
+	}
+}
@@ -0,0 +1,135 @@
+// Package generate renders synthetic records from text/template templates
+// with functions for sequences, uuids, timestamps, random values and fake
+// personal data.
+package generate
+
+import (
+	"crypto/rand"
+	"fmt"
+	mrand "math/rand"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Generator renders the templates of one record after another, seq, key and
+// now stay the same within a record. It is not safe for concurrent use.
+type Generator struct {
+	rnd   *mrand.Rand
+	keys  *Keys
+	funcs template.FuncMap
+
+	seq int64
+	key uint64
+	now time.Time
+}
+
+// New returns a generator drawing from rnd, the key ids from keys.
+func New(rnd *mrand.Rand, keys *Keys) *Generator {
+	g := &Generator{rnd: rnd, keys: keys, seq: -1}
+	g.funcs = template.FuncMap{
+		"seq":        func() int64 { return g.seq },
+		"key":        func() uint64 { return g.key },
+		"uuid":       g.uuid,
+		"now":        func() time.Time { return g.now },
+		"timestamp":  func() string { return g.now.Format(time.RFC3339Nano) },
+		"unixMillis": func() int64 { return g.now.UnixNano() / int64(time.Millisecond) },
+		"int":        g.int,
+		"float":      g.float,
+		"string":     g.string,
+		"bool":       func() bool { return g.rnd.Intn(2) == 1 },
+		"pick":       g.pick,
+	}
+	for name, f := range fake(rnd) {
+		g.funcs[name] = f
+	}
+	return g
+}
+
+// Parse parses a template, it is executed once to report errors of the
+// function arguments before the first record.
+func (g *Generator) Parse(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(g.funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	seq, key, now := g.seq, g.key, g.now
+	defer func() { g.seq, g.key, g.now = seq, key, now }()
+	g.now = time.Now()
+	if _, err := g.Render(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Next moves on to the next record.
+func (g *Generator) Next() {
+	g.seq++
+	if g.keys != nil {
+		g.key = g.keys.Next()
+	}
+	g.now = time.Now()
+}
+
+// Render executes a template for the current record.
+func (g *Generator) Render(t *template.Template) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, nil); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// uuid returns a random version 4 uuid.
+func (g *Generator) uuid() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
+
+// int returns a random integer from min to max, both included.
+func (g *Generator) int(min, max int64) (int64, error) {
+	if min > max {
+		return 0, fmt.Errorf("int: min %d is greater than max %d", min, max)
+	}
+	n := max - min + 1
+	if n <= 0 {
+		// the range overflows int64
+		return 0, fmt.Errorf("int: the range from %d to %d is too large", min, max)
+	}
+	return min + g.rnd.Int63n(n), nil
+}
+
+// float returns a random number from min up to max.
+func (g *Generator) float(min, max float64) (float64, error) {
+	if min > max {
+		return 0, fmt.Errorf("float: min %g is greater than max %g", min, max)
+	}
+	return min + g.rnd.Float64()*(max-min), nil
+}
+
+// string returns random letters and digits, from min to max of them.
+func (g *Generator) string(min, max int) (string, error) {
+	if min < 0 || min > max {
+		return "", fmt.Errorf("string: the length should be from 0 and min should not be greater than max, got %d and %d", min, max)
+	}
+	b := make([]byte, min+g.rnd.Intn(max-min+1))
+	for i := range b {
+		b[i] = alphanumeric[g.rnd.Intn(len(alphanumeric))]
+	}
+	return string(b), nil
+}
+
+// pick returns one of its arguments at random.
+func (g *Generator) pick(values ...interface{}) (interface{}, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("pick: no values to pick from")
+	}
+	return values[g.rnd.Intn(len(values))], nil
+}
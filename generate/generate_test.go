@@ -0,0 +1,135 @@
+package generate
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func newTestGenerator(t *testing.T) *Generator {
+	keys, err := NewKeys(DistributionUniform, 1000000, 0, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(rand.New(rand.NewSource(1)), keys)
+}
+
+func TestRecordValuesFixed(t *testing.T) {
+	g := newTestGenerator(t)
+	key, err := g.Parse("key", `{{seq}}/{{key}}/{{timestamp}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := g.Parse("value", `{{seq}}/{{key}}/{{now.Format "2006-01-02T15:04:05.999999999Z07:00"}} {{seq}}/{{key}}/{{timestamp}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	render := func(tmpl string) string {
+		t.Helper()
+		tt := key
+		if tmpl == "value" {
+			tt = value
+		}
+		s, err := g.Render(tt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		g.Next()
+		k := render("key")
+		if !strings.HasPrefix(k, string(rune('0'+i))+"/") {
+			t.Errorf("record %d has key %q", i, k)
+		}
+		// seq, key and now are the same in every template and call of a record
+		if v := render("value"); v != k+" "+k {
+			t.Errorf("record %d: value %q, want %q twice", i, v, k)
+		}
+		if again := render("key"); again != k {
+			t.Errorf("record %d rendered %q, then %q", i, k, again)
+		}
+		seen[k] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("5 records rendered %d distinct keys", len(seen))
+	}
+}
+
+func TestParseKeepsRecord(t *testing.T) {
+	g := newTestGenerator(t)
+	g.Next()
+	tmpl, err := g.Parse("value", `{{seq}}-{{key}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, _ := g.Render(tmpl)
+	// parsing executes the template once, which does not move on
+	if _, err := g.Parse("other", `{{seq}}`); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := g.Render(tmpl); after != before || !strings.HasPrefix(before, "0-") {
+		t.Errorf("rendered %q before parsing, %q after", before, after)
+	}
+}
+
+func TestFunctionArguments(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{`{{int 1 6}}`, ""},
+		{`{{int -5 -5}}`, ""},
+		{`{{int 6 1}}`, "int: min 6 is greater than max 1"},
+		{`{{int -9223372036854775808 9223372036854775807}}`, "int: the range from -9223372036854775808 to 9223372036854775807 is too large"},
+		{`{{float 0.5 0.5}}`, ""},
+		{`{{float 2 1}}`, "float: min 2 is greater than max 1"},
+		{`{{string 0 0}}`, ""},
+		{`{{string 3 8}}`, ""},
+		{`{{string -1 3}}`, "string: the length should be from 0"},
+		{`{{string 5 4}}`, "string: the length should be from 0"},
+		{`{{pick "a" 1 true}}`, ""},
+		{`{{pick}}`, "pick: no values to pick from"},
+		{`{{int "1" 6}}`, "expected integer"},
+		{`{{unknown}}`, `function "unknown" not defined`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			_, err := newTestGenerator(t).Parse("value", tt.text)
+			if tt.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestFunctionRanges(t *testing.T) {
+	g := newTestGenerator(t)
+	for i := 0; i < 1000; i++ {
+		if v, _ := g.int(-2, 2); v < -2 || v > 2 {
+			t.Fatalf("int(-2, 2) = %d", v)
+		}
+		if v, _ := g.int(math.MaxInt64-1, math.MaxInt64); v < math.MaxInt64-1 {
+			t.Fatalf("int at the top of the range = %d", v)
+		}
+		if v, _ := g.float(1, 2); v < 1 || v >= 2 {
+			t.Fatalf("float(1, 2) = %v", v)
+		}
+		if s, _ := g.string(2, 4); len(s) < 2 || len(s) > 4 || strings.Trim(s, alphanumeric) != "" {
+			t.Fatalf("string(2, 4) = %q", s)
+		}
+	}
+	u, err := g.uuid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u) != 36 || u[14] != '4' || !strings.Contains("89ab", u[19:20]) {
+		t.Errorf("uuid %q is not a version 4 uuid", u)
+	}
+}
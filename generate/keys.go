@@ -0,0 +1,39 @@
+package generate
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// The distributions of the key ids.
+const (
+	DistributionUniform = "uniform"
+	DistributionZipf    = "zipf"
+)
+
+// Keys draws the key id of every record from 0 to n-1. With zipf the low ids
+// are hot, 0 the hottest, the higher the exponent the hotter.
+type Keys struct {
+	next func() uint64
+}
+
+func NewKeys(distribution string, n uint64, exponent float64, rnd *rand.Rand) (*Keys, error) {
+	if n == 0 {
+		return nil, fmt.Errorf("the number of keys should be positive")
+	}
+	switch distribution {
+	case DistributionUniform:
+		return &Keys{next: func() uint64 { return uint64(rnd.Int63n(int64(n))) }}, nil
+	case DistributionZipf:
+		if exponent <= 1 {
+			return nil, fmt.Errorf("the zipf exponent should be greater than 1")
+		}
+		zipf := rand.NewZipf(rnd, exponent, 1, n-1)
+		return &Keys{next: zipf.Uint64}, nil
+	}
+	return nil, fmt.Errorf("unknown key distribution %q, should be %s or %s", distribution, DistributionUniform, DistributionZipf)
+}
+
+func (k *Keys) Next() uint64 {
+	return k.next()
+}
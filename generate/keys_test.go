@@ -0,0 +1,78 @@
+package generate
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestNewKeys(t *testing.T) {
+	tests := []struct {
+		distribution string
+		n            uint64
+		exponent     float64
+		err          string
+	}{
+		{DistributionUniform, 10, 0, ""},
+		{DistributionZipf, 10, 1.1, ""},
+		{DistributionUniform, 0, 0, "the number of keys should be positive"},
+		{DistributionZipf, 10, 1, "the zipf exponent should be greater than 1"},
+		{"normal", 10, 0, `unknown key distribution "normal", should be uniform or zipf`},
+	}
+	for _, tt := range tests {
+		_, err := NewKeys(tt.distribution, tt.n, tt.exponent, rand.New(rand.NewSource(1)))
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: %v", tt.distribution, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: err = %v, want %q", tt.distribution, err, tt.err)
+		}
+	}
+}
+
+// drawKeys counts the key ids of draws records.
+func drawKeys(t *testing.T, distribution string, n uint64, exponent float64, draws int) []int {
+	t.Helper()
+	keys, err := NewKeys(distribution, n, exponent, rand.New(rand.NewSource(42)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make([]int, n)
+	for i := 0; i < draws; i++ {
+		k := keys.Next()
+		if k >= n {
+			t.Fatalf("key id %d is not below %d", k, n)
+		}
+		counts[k]++
+	}
+	return counts
+}
+
+func TestZipfKeys(t *testing.T) {
+	const n, draws = 100, 100000
+	for _, exponent := range []float64{1.1, 1.5, 3} {
+		counts := drawKeys(t, DistributionZipf, n, exponent, draws)
+		// key 0 is the hottest, and the hotter the higher the exponent
+		for k := 1; k < n; k++ {
+			if counts[k] >= counts[0] {
+				t.Errorf("exponent %v: key %d drawn %d times, key 0 only %d", exponent, k, counts[k], counts[0])
+			}
+		}
+		if counts[1] <= counts[10] || counts[10] < counts[n-1] {
+			t.Errorf("exponent %v: keys 1, 10 and %d drawn %d, %d and %d times", exponent, n-1, counts[1], counts[10], counts[n-1])
+		}
+		if exponent == 3 && counts[0] < draws*3/4 {
+			t.Errorf("exponent 3: key 0 drawn %d of %d times", counts[0], draws)
+		}
+	}
+}
+
+func TestUniformKeys(t *testing.T) {
+	const n, draws = 10, 100000
+	for k, c := range drawKeys(t, DistributionUniform, n, 0, draws) {
+		// within 5% of the expected draws
+		if c < draws/n*95/100 || c > draws/n*105/100 {
+			t.Errorf("key %d drawn %d of %d times", k, c, draws)
+		}
+	}
+}
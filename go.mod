@@ -4,7 +4,12 @@ go 1.15
 
 require (
 	github.com/Shopify/sarama v1.27.2
+	github.com/linkedin/goavro/v2 v2.9.8
 	github.com/spf13/cobra v1.1.1
+	github.com/spf13/pflag v1.0.5
 	go.uber.org/zap v1.16.0
+	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a
 	golang.org/x/net v0.0.0-20200904194848-62affa334b73
+	google.golang.org/protobuf v1.27.1
+	gopkg.in/yaml.v2 v2.4.0
 )
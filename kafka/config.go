@@ -0,0 +1,298 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/Shopify/sarama"
+	"github.com/spf13/pflag"
+	"github.com/thimico/kafka-cli/config"
+	"github.com/thimico/kafka-cli/log"
+	"github.com/thimico/kafka-cli/serde"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultBootstrapServers = "localhost:9092"
+
+// ClientOptions holds the connection settings shared by every command,
+// they are bound to the root command as persistent flags.
+type ClientOptions struct {
+	ConfigFile       string
+	Context          string
+	BootstrapServers string
+	KafkaVersion     string
+	Verbose          bool
+	ClientConfigFile string
+	ClientProperties []string
+	Timeout          time.Duration
+
+	TLSEnabled            bool
+	TLSCAFile             string
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSInsecureSkipVerify bool
+	TLSServerName         string
+
+	SASLMechanism string
+	SASLUsername  string
+	SASLPassword  string
+
+	SchemaRegistryURL      string
+	SchemaRegistryUsername string
+	SchemaRegistryPassword string
+
+	detectedVersion *sarama.KafkaVersion
+}
+
+func NewClientOptions() *ClientOptions {
+	return &ClientOptions{}
+}
+
+func (o *ClientOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.ConfigFile, "config", o.ConfigFile, "The config file holding the cluster contexts (default ~/.config/kafka-cli/config.yaml)")
+	flags.StringVar(&o.Context, "context", o.Context, "The context in the config file to use instead of the current context")
+	flags.StringVarP(&o.BootstrapServers, "bootstrap-servers", "b", defaultBootstrapServers, "The Kafka server to connect to.more than one should be separated by commas")
+	flags.StringVar(&o.KafkaVersion, "kafka-version", o.KafkaVersion, "The Kafka protocol version to use, e.g. 2.6.0, or auto to detect it from the brokers (default 1.0.0)")
+	flags.BoolVar(&o.Verbose, "verbose", o.Verbose, "Log connection details, such as the detected protocol version and the api versions of the broker, and the effective producer and perf consumer config")
+	flags.StringVar(&o.ClientConfigFile, "client-config", o.ClientConfigFile, "A java style properties file of client properties, see --client-property")
+	flags.DurationVar(&o.Timeout, "timeout", o.Timeout, "Abort the command after this duration, e.g. 30s, consumers stop cleanly when it expires (default no timeout)")
+	flags.StringArrayVar(&o.ClientProperties, "client-property", o.ClientProperties, "A client property as key=value, e.g. fetch.min.bytes=1024, can be repeated and takes precedence over --client-config")
+	flags.BoolVar(&o.TLSEnabled, "tls", o.TLSEnabled, "Connect to the brokers over TLS, implied by any other --tls-* flag")
+	flags.StringVar(&o.TLSCAFile, "tls-ca-file", o.TLSCAFile, "PEM encoded CA certificates used to verify the brokers")
+	flags.StringVar(&o.TLSCertFile, "tls-cert-file", o.TLSCertFile, "PEM encoded client certificate, used together with --tls-key-file")
+	flags.StringVar(&o.TLSKeyFile, "tls-key-file", o.TLSKeyFile, "PEM encoded client private key, used together with --tls-cert-file")
+	flags.BoolVar(&o.TLSInsecureSkipVerify, "tls-insecure-skip-verify", o.TLSInsecureSkipVerify, "Do not verify the broker certificate chain and host name")
+	flags.StringVar(&o.TLSServerName, "tls-server-name", o.TLSServerName, "The server name (SNI) sent during the TLS handshake, defaults to the broker host")
+	flags.StringVar(&o.SASLMechanism, "sasl-mechanism", o.SASLMechanism, "The SASL mechanism to authenticate with. Can be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512")
+	flags.StringVar(&o.SASLUsername, "sasl-username", o.SASLUsername, "The SASL username")
+	flags.StringVar(&o.SASLPassword, "sasl-password", o.SASLPassword, "The SASL password")
+	flags.StringVar(&o.SchemaRegistryURL, "schema-registry-url", o.SchemaRegistryURL, "The Schema Registry to look up the schemas of the avro format in, e.g. http://localhost:8081")
+	flags.StringVar(&o.SchemaRegistryUsername, "schema-registry-username", o.SchemaRegistryUsername, "The basic auth username of the Schema Registry")
+	flags.StringVar(&o.SchemaRegistryPassword, "schema-registry-password", o.SchemaRegistryPassword, "The basic auth password of the Schema Registry")
+}
+
+// NormalizeFlagName keeps the old --bootstrap-server spelling working.
+func NormalizeFlagName(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+	if name == "bootstrap-server" {
+		name = "bootstrap-servers"
+	}
+	return pflag.NormalizedName(name)
+}
+
+// Complete fills every flag which is not given on the command line, first from
+// its KAFKA_CLI_* environment variable and then from the selected context.
+func (o *ClientOptions) Complete(flags *pflag.FlagSet) error {
+	if v, ok := os.LookupEnv(config.EnvName("context")); ok && !flags.Changed("context") {
+		o.Context = v
+	}
+	if o.ConfigFile == "" {
+		o.ConfigFile = config.DefaultPath()
+	}
+	cfg, err := config.Load(o.ConfigFile)
+	if err != nil {
+		return err
+	}
+	ctx, err := cfg.Context(o.Context)
+	if err != nil {
+		return err
+	}
+	var contextValues map[string]string
+	if ctx != nil {
+		contextValues = ctx.FlagValues()
+	}
+
+	var setErr error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if setErr != nil || f.Changed || f.Name == "config" || f.Name == "context" {
+			return
+		}
+		value, ok := os.LookupEnv(config.EnvName(f.Name))
+		if !ok {
+			value, ok = contextValues[f.Name]
+		}
+		if ok {
+			if err := flags.Set(f.Name, value); err != nil {
+				setErr = fmt.Errorf("invalid value %q for --%s: %s", value, f.Name, err)
+			}
+		}
+	})
+	return setErr
+}
+
+// Brokers returns the bootstrap servers as a list.
+func (o *ClientOptions) Brokers() []string {
+	return strings.Split(o.BootstrapServers, ",")
+}
+
+// SchemaRegistry returns a client of the Schema Registry, or nil when no
+// --schema-registry-url is configured.
+func (o *ClientOptions) SchemaRegistry() *serde.Registry {
+	if o.SchemaRegistryURL == "" {
+		return nil
+	}
+	return serde.NewRegistry(o.SchemaRegistryURL, o.SchemaRegistryUsername, o.SchemaRegistryPassword)
+}
+
+// NewConfig builds the sarama config every command starts from.
+func (o *ClientOptions) NewConfig() (*sarama.Config, error) {
+	config := sarama.NewConfig()
+	// producers wait for every in sync replica unless the acks property says otherwise
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	if o.KafkaVersion != "" && o.KafkaVersion != VersionAuto {
+		version, err := sarama.ParseKafkaVersion(o.KafkaVersion)
+		if err != nil {
+			return nil, err
+		}
+		config.Version = version
+	}
+	if o.tlsEnabled() {
+		tlsConfig, err := o.newTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+	if o.SASLMechanism != "" {
+		if err := o.applySASL(config); err != nil {
+			return nil, err
+		}
+	}
+	if err := o.applyClientProperties(config); err != nil {
+		return nil, err
+	}
+	if o.KafkaVersion == VersionAuto {
+		version, err := o.detectVersion(config)
+		if err != nil {
+			return nil, err
+		}
+		config.Version = version
+	}
+	if config.Net.SASL.Enable {
+		if err := setSASLHandshake(config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (o *ClientOptions) applyClientProperties(config *sarama.Config) error {
+	if o.ClientConfigFile != "" {
+		properties, err := ReadPropertiesFile(o.ClientConfigFile)
+		if err != nil {
+			return err
+		}
+		for _, p := range properties {
+			if err := ApplyClientProperty(config, p[0], p[1]); err != nil {
+				return fmt.Errorf("%s: %s", o.ClientConfigFile, err)
+			}
+		}
+	}
+	for _, kv := range o.ClientProperties {
+		key, value, err := ParseClientProperty(kv)
+		if err != nil {
+			return err
+		}
+		if err := ApplyClientProperty(config, key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// detectVersion runs the version detection once per process, every command
+// building more than one config reuses the first result.
+func (o *ClientOptions) detectVersion(config *sarama.Config) (sarama.KafkaVersion, error) {
+	if o.detectedVersion != nil {
+		return *o.detectedVersion, nil
+	}
+	version, apiVersions, err := DetectVersion(o.Brokers(), config)
+	if err != nil {
+		return version, err
+	}
+	if o.Verbose {
+		log.Info("Detected kafka version", zap.String("version", version.String()))
+		for _, v := range apiVersions {
+			log.Info("Broker api version", zap.String("api", ApiKeyName(v.ApiKey)), zap.Int16("key", v.ApiKey),
+				zap.Int16("min", v.MinVersion), zap.Int16("max", v.MaxVersion))
+		}
+	}
+	o.detectedVersion = &version
+	return version, nil
+}
+
+func (o *ClientOptions) tlsEnabled() bool {
+	return o.TLSEnabled || o.TLSCAFile != "" || o.TLSCertFile != "" || o.TLSKeyFile != "" ||
+		o.TLSInsecureSkipVerify || o.TLSServerName != ""
+}
+
+func (o *ClientOptions) newTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: o.TLSInsecureSkipVerify,
+		ServerName:         o.TLSServerName,
+	}
+	if o.TLSCAFile != "" {
+		pem, err := ioutil.ReadFile(o.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read tls ca file: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", o.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if (o.TLSCertFile == "") != (o.TLSKeyFile == "") {
+		return nil, errors.New("--tls-cert-file and --tls-key-file should be specified together")
+	}
+	if o.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.TLSCertFile, o.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load tls client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// setSASLHandshake picks the SASL handshake of config.Version. Brokers before
+// 1.0 only know handshake v0, which sarama supports for PLAIN only, SCRAM
+// needs v1.
+func setSASLHandshake(config *sarama.Config) error {
+	if config.Version.IsAtLeast(sarama.V1_0_0_0) {
+		config.Net.SASL.Version = sarama.SASLHandshakeV1
+		return nil
+	}
+	if config.Net.SASL.Mechanism != sarama.SASLTypePlaintext {
+		return fmt.Errorf("sasl mechanism %s is supported from kafka 1.0 on, the kafka version is %s", config.Net.SASL.Mechanism, config.Version)
+	}
+	config.Net.SASL.Version = sarama.SASLHandshakeV0
+	return nil
+}
+
+func (o *ClientOptions) applySASL(config *sarama.Config) error {
+	mechanism := sarama.SASLMechanism(strings.ToUpper(o.SASLMechanism))
+	switch mechanism {
+	case sarama.SASLTypePlaintext:
+	case sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512:
+		config.Net.SASL.SCRAMClientGeneratorFunc = newSCRAMClientGenerator(mechanism)
+	default:
+		return fmt.Errorf("unsupported sasl mechanism %q, should be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512", o.SASLMechanism)
+	}
+	if o.SASLUsername == "" {
+		return errors.New("--sasl-username should not be empty when --sasl-mechanism is specified")
+	}
+	config.Net.SASL.Enable = true
+	config.Net.SASL.Handshake = true
+	// v1 until the version is known, see setSASLHandshake
+	config.Net.SASL.Version = sarama.SASLHandshakeV1
+	config.Net.SASL.Mechanism = mechanism
+	config.Net.SASL.User = o.SASLUsername
+	config.Net.SASL.Password = o.SASLPassword
+	return nil
+}
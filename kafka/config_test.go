@@ -0,0 +1,35 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestSASLHandshake(t *testing.T) {
+	tests := []struct {
+		version, mechanism string
+		want               int16
+		err                string
+	}{
+		{"0.10.2.0", "PLAIN", sarama.SASLHandshakeV0, ""},
+		{"0.11.0.2", "plain", sarama.SASLHandshakeV0, ""},
+		{"1.0.0", "PLAIN", sarama.SASLHandshakeV1, ""},
+		{"2.6.0", "SCRAM-SHA-512", sarama.SASLHandshakeV1, ""},
+		{"1.0.0", "SCRAM-SHA-256", sarama.SASLHandshakeV1, ""},
+		{"0.11.0.0", "SCRAM-SHA-256", 0, "sasl mechanism SCRAM-SHA-256 is supported from kafka 1.0 on, the kafka version is 0.11.0.0"},
+		{"0.10.2.0", "SCRAM-SHA-512", 0, "is supported from kafka 1.0 on"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.mechanism, func(t *testing.T) {
+			o := &ClientOptions{KafkaVersion: tt.version, SASLMechanism: tt.mechanism, SASLUsername: "alice"}
+			config, err := o.NewConfig()
+			if !errorContains(err, tt.err) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+			if err == nil && config.Net.SASL.Version != tt.want {
+				t.Errorf("handshake version = %d, want %d", config.Net.SASL.Version, tt.want)
+			}
+		})
+	}
+}
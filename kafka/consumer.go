@@ -1,6 +1,14 @@
 package kafka
 
-import "github.com/Shopify/sarama"
+import (
+	"fmt"
+	"github.com/Shopify/sarama"
+	"sync"
+)
+
+func NewClient(addrs []string, config *sarama.Config) (sarama.Client, error) {
+	return sarama.NewClient(addrs, config)
+}
 
 func NewConsumer(addrs []string, config *sarama.Config) (sarama.Consumer, error) {
 	c, err := sarama.NewConsumer(addrs, config)
@@ -10,6 +18,12 @@ func NewConsumer(addrs []string, config *sarama.Config) (sarama.Consumer, error)
 	return c, err
 }
 
+// NewConsumerFromClient returns a consumer sharing client, closing the
+// consumer leaves the client open.
+func NewConsumerFromClient(client sarama.Client) (sarama.Consumer, error) {
+	return sarama.NewConsumerFromClient(client)
+}
+
 func NewConsumerGroup(addrs []string, groupID string, config *sarama.Config) (sarama.ConsumerGroup, error) {
 	g, err := sarama.NewConsumerGroup(addrs, groupID, config)
 	if err != nil {
@@ -17,3 +31,78 @@ func NewConsumerGroup(addrs []string, groupID string, config *sarama.Config) (sa
 	}
 	return g, err
 }
+
+// PartitionConsumers fans in the messages of one sarama.PartitionConsumer per
+// partition. Messages of a partition keep their order.
+type PartitionConsumers struct {
+	consumers map[TopicPartition]sarama.PartitionConsumer
+	messages  chan *sarama.ConsumerMessage
+	errors    chan *sarama.ConsumerError
+	wg        sync.WaitGroup
+}
+
+// ConsumePartitions starts consuming every partition of offsets at its offset.
+func ConsumePartitions(c sarama.Consumer, offsets map[TopicPartition]int64) (*PartitionConsumers, error) {
+	pcs := &PartitionConsumers{
+		consumers: map[TopicPartition]sarama.PartitionConsumer{},
+		messages:  make(chan *sarama.ConsumerMessage),
+		errors:    make(chan *sarama.ConsumerError),
+	}
+	for tp, offset := range offsets {
+		pc, err := c.ConsumePartition(tp.Topic, tp.Partition, offset)
+		if err != nil {
+			pcs.Close()
+			return nil, fmt.Errorf("consume %s: %w", tp, err)
+		}
+		pcs.consumers[tp] = pc
+		pcs.wg.Add(2)
+		go func() {
+			defer pcs.wg.Done()
+			for msg := range pc.Messages() {
+				pcs.messages <- msg
+			}
+		}()
+		go func() {
+			defer pcs.wg.Done()
+			for err := range pc.Errors() {
+				pcs.errors <- err
+			}
+		}()
+	}
+	return pcs, nil
+}
+
+func (pcs *PartitionConsumers) Messages() <-chan *sarama.ConsumerMessage {
+	return pcs.messages
+}
+
+// Errors returns the consume errors, config.Consumer.Return.Errors must be set.
+func (pcs *PartitionConsumers) Errors() <-chan *sarama.ConsumerError {
+	return pcs.errors
+}
+
+// Consumer returns the partition consumer of tp, or nil.
+func (pcs *PartitionConsumers) Consumer(tp TopicPartition) sarama.PartitionConsumer {
+	return pcs.consumers[tp]
+}
+
+// Close stops every partition consumer and discards the messages which are
+// already fetched but not read.
+func (pcs *PartitionConsumers) Close() error {
+	for _, pc := range pcs.consumers {
+		pc.AsyncClose()
+	}
+	done := make(chan struct{})
+	go func() {
+		pcs.wg.Wait()
+		close(done)
+	}()
+	for {
+		select {
+		case <-pcs.messages:
+		case <-pcs.errors:
+		case <-done:
+			return nil
+		}
+	}
+}
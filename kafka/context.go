@@ -0,0 +1,56 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+)
+
+// NewContext derives the context of a command from the root context, bounded
+// by --timeout when it is set.
+func (o *ClientOptions) NewContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if o.Timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, o.Timeout)
+}
+
+// Wait runs f and returns its error, or the error of ctx when it is done first.
+// sarama calls do not take a context, so f keeps running until the client it
+// uses is closed.
+func Wait(ctx context.Context, f func() error) error {
+	return WaitOpen(ctx, f, nil)
+}
+
+// WaitOpen is Wait for an f which opens a client, consumer or producer. When
+// ctx is done first nobody uses what f opens, so cleanup closes it once f
+// succeeds after all.
+func WaitOpen(ctx context.Context, f func() error, cleanup func()) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if cleanup != nil {
+			go func() {
+				if err := <-done; err == nil {
+					cleanup()
+				}
+			}()
+		}
+		return ContextError(ctx)
+	}
+}
+
+// ContextError describes why ctx is done.
+func ContextError(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("timed out: %w", ctx.Err())
+	case context.Canceled:
+		return fmt.Errorf("interrupted: %w", ctx.Err())
+	}
+	return ctx.Err()
+}
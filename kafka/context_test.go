@@ -0,0 +1,67 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitOpen(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name string
+		// late makes f return after the deadline
+		late    bool
+		err     error
+		wantErr error
+		cleanup bool
+	}{
+		{"opened in time", false, nil, nil, false},
+		{"failed in time", false, failed, failed, false},
+		{"opened late", true, nil, context.DeadlineExceeded, true},
+		{"failed late", true, failed, context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			release := make(chan struct{})
+			cleaned := make(chan struct{})
+			err := WaitOpen(ctx, func() error {
+				if tt.late {
+					<-release
+				}
+				return tt.err
+			}, func() { close(cleaned) })
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			close(release)
+			select {
+			case <-cleaned:
+				if !tt.cleanup {
+					t.Error("cleaned up")
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.cleanup {
+					t.Error("not cleaned up after f opened late")
+				}
+			}
+		})
+	}
+}
+
+func TestContextError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ContextError(ctx); err.Error() != "interrupted: context canceled" {
+		t.Errorf("err = %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+	if err := ContextError(ctx); !errors.Is(err, context.DeadlineExceeded) || err.Error() != "timed out: context deadline exceeded" {
+		t.Errorf("err = %v", err)
+	}
+}
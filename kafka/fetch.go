@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Shopify/sarama"
+)
+
+// OffsetsSkipped reports whether a consumer of client's config, fetching tp
+// from offset from, skips every offset below to. It does when the offsets
+// hold only control records like transaction markers, records of aborted
+// transactions with read_committed, or nothing at all because compaction
+// removed them. A consumer which delivered nothing at or after from is then
+// done with the offsets below to, although sarama does not tell how far it
+// fetched. Offsets the partition does not have yet are not skipped.
+func OffsetsSkipped(client sarama.Client, tp TopicPartition, from, to int64) (bool, error) {
+	broker, err := client.Leader(tp.Topic, tp.Partition)
+	if err != nil {
+		return false, err
+	}
+	config := client.Config()
+	for from < to {
+		req := newFetchRequest(config)
+		req.AddBlock(tp.Topic, tp.Partition, from, config.Consumer.Fetch.Default)
+		res, err := broker.Fetch(req)
+		if err != nil {
+			return false, err
+		}
+		block := res.GetBlock(tp.Topic, tp.Partition)
+		if block == nil {
+			return false, fmt.Errorf("fetch %s: no partition in the response", tp)
+		}
+		if block.Err != sarama.ErrNoError {
+			return false, fmt.Errorf("fetch %s: %w", tp, block.Err)
+		}
+		// read_committed fetches stop at the last stable offset, before the
+		// first open transaction
+		end := block.HighWaterMarkOffset
+		if req.Version >= 4 && req.Isolation == sarama.ReadCommitted {
+			end = block.LastStableOffset
+		}
+		if end < to {
+			return false, nil
+		}
+		next, deliverable := scanRecords(block, config.Consumer.IsolationLevel, from, to)
+		if deliverable {
+			return false, nil
+		}
+		if next <= from {
+			// nothing complete fetched below to, which is only the case when
+			// the partition has no record there or the record is larger than
+			// the fetch size, the consumer is not done with the latter
+			return len(block.RecordsSet) == 0, nil
+		}
+		from = next
+	}
+	return true, nil
+}
+
+// newFetchRequest returns a fetch request of the version sarama's consumer
+// sends for config.
+func newFetchRequest(config *sarama.Config) *sarama.FetchRequest {
+	req := &sarama.FetchRequest{MinBytes: 1, MaxBytes: sarama.MaxResponseSize}
+	switch {
+	case config.Version.IsAtLeast(sarama.V0_11_0_0):
+		req.Version = 4
+		req.Isolation = config.Consumer.IsolationLevel
+	case config.Version.IsAtLeast(sarama.V0_10_1_0):
+		req.Version = 3
+	case config.Version.IsAtLeast(sarama.V0_10_0_0):
+		req.Version = 2
+	case config.Version.IsAtLeast(sarama.V0_9_0_0):
+		req.Version = 1
+	}
+	return req
+}
+
+// scanRecords reports whether block has a record from..to a consumer
+// delivers, and otherwise the offset after the last record it has.
+func scanRecords(block *sarama.FetchResponseBlock, isolation sarama.IsolationLevel, from, to int64) (next int64, deliverable bool) {
+	next = from
+	aborted := append([]*sarama.AbortedTransaction(nil), block.AbortedTransactions...)
+	sort.Slice(aborted, func(i, j int) bool { return aborted[i].FirstOffset < aborted[j].FirstOffset })
+	abortedProducers := map[int64]bool{}
+	for _, records := range block.RecordsSet {
+		if set := records.MsgSet; set != nil {
+			// the old formats have no control records, only compaction
+			// leaves gaps, a compressed message holds the offsets below its own
+			for _, msg := range set.Messages {
+				if msg.Offset >= from && (msg.Offset < to || msg.Msg.Set != nil) {
+					return next, true
+				}
+				if msg.Offset+1 > next {
+					next = msg.Offset + 1
+				}
+			}
+			continue
+		}
+		batch := records.RecordBatch
+		if batch == nil || batch.PartialTrailingRecord {
+			continue
+		}
+		last := batch.FirstOffset + int64(batch.LastOffsetDelta)
+		for len(aborted) > 0 && aborted[0].FirstOffset <= last {
+			abortedProducers[aborted[0].ProducerID] = true
+			aborted = aborted[1:]
+		}
+		// the same records sarama's consumer does not deliver
+		skipped := batch.Control ||
+			(isolation == sarama.ReadCommitted && batch.IsTransactional && abortedProducers[batch.ProducerID])
+		if batch.Control && isAbortMarker(batch) {
+			delete(abortedProducers, batch.ProducerID)
+		}
+		if !skipped {
+			for _, r := range batch.Records {
+				if offset := batch.FirstOffset + r.OffsetDelta; offset >= from && offset < to {
+					return next, true
+				}
+			}
+		}
+		if last+1 > next {
+			next = last + 1
+		}
+	}
+	return next, false
+}
+
+// isAbortMarker reports whether a control batch ends an aborted transaction,
+// the key of a control record is its version and type, both int16.
+func isAbortMarker(batch *sarama.RecordBatch) bool {
+	if len(batch.Records) == 0 || len(batch.Records[0].Key) < 4 {
+		return false
+	}
+	key := batch.Records[0].Key
+	return int16(key[2])<<8|int16(key[3]) == int16(sarama.ControlRecordAbort)
+}
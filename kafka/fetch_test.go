@@ -0,0 +1,101 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestOffsetsSkipped(t *testing.T) {
+	tp := TopicPartition{Topic: "t", Partition: 0}
+	record := func(res *sarama.FetchResponse, offset, producerID int64) {
+		res.AddRecordBatch(tp.Topic, tp.Partition, nil, sarama.StringEncoder("v"), offset, producerID, producerID > 0)
+	}
+	marker := func(res *sarama.FetchResponse, offset, producerID int64, typ sarama.ControlRecordType) {
+		res.AddControlRecord(tp.Topic, tp.Partition, offset, producerID, typ)
+	}
+	tests := []struct {
+		name      string
+		isolation sarama.IsolationLevel
+		from      int64
+		// fill adds the records of offset from on to the response
+		fill    func(res *sarama.FetchResponse)
+		hwm     int64
+		lso     int64
+		skipped bool
+		err     bool
+	}{
+		{"commit marker", sarama.ReadUncommitted, 9, func(res *sarama.FetchResponse) {
+			marker(res, 9, 1, sarama.ControlRecordCommit)
+		}, 10, 10, true, false},
+		{"record before the marker", sarama.ReadUncommitted, 8, func(res *sarama.FetchResponse) {
+			record(res, 8, 1)
+			marker(res, 9, 1, sarama.ControlRecordCommit)
+		}, 10, 10, false, false},
+		{"aborted read_committed", sarama.ReadCommitted, 7, func(res *sarama.FetchResponse) {
+			record(res, 7, 1)
+			record(res, 8, 1)
+			marker(res, 9, 1, sarama.ControlRecordAbort)
+			res.GetBlock(tp.Topic, tp.Partition).AbortedTransactions = []*sarama.AbortedTransaction{{ProducerID: 1, FirstOffset: 7}}
+		}, 10, 10, true, false},
+		{"aborted read_uncommitted", sarama.ReadUncommitted, 7, func(res *sarama.FetchResponse) {
+			record(res, 7, 1)
+			record(res, 8, 1)
+			marker(res, 9, 1, sarama.ControlRecordAbort)
+		}, 10, 10, false, false},
+		{"committed after an abort", sarama.ReadCommitted, 6, func(res *sarama.FetchResponse) {
+			record(res, 6, 1)
+			marker(res, 7, 1, sarama.ControlRecordAbort)
+			record(res, 8, 1)
+			marker(res, 9, 1, sarama.ControlRecordCommit)
+			res.GetBlock(tp.Topic, tp.Partition).AbortedTransactions = []*sarama.AbortedTransaction{{ProducerID: 1, FirstOffset: 6}}
+		}, 10, 10, false, false},
+		{"record at the bound", sarama.ReadUncommitted, 9, func(res *sarama.FetchResponse) {
+			marker(res, 9, 1, sarama.ControlRecordCommit)
+			record(res, 10, 0)
+		}, 11, 11, true, false},
+		{"compacted", sarama.ReadUncommitted, 8, func(res *sarama.FetchResponse) {}, 10, 10, true, false},
+		{"not written yet", sarama.ReadUncommitted, 9, func(res *sarama.FetchResponse) {
+			marker(res, 9, 1, sarama.ControlRecordCommit)
+		}, 9, 9, false, false},
+		{"open transaction", sarama.ReadCommitted, 9, func(res *sarama.FetchResponse) {}, 10, 9, false, false},
+		{"out of range", sarama.ReadUncommitted, 9, func(res *sarama.FetchResponse) {
+			res.AddError(tp.Topic, tp.Partition, sarama.ErrOffsetOutOfRange)
+		}, 0, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &sarama.FetchResponse{Version: 4}
+			res.AddError(tp.Topic, tp.Partition, sarama.ErrNoError)
+			tt.fill(res)
+			block := res.GetBlock(tp.Topic, tp.Partition)
+			block.HighWaterMarkOffset = tt.hwm
+			block.LastStableOffset = tt.lso
+
+			broker := sarama.NewMockBroker(t, 1)
+			defer broker.Close()
+			broker.SetHandlerByMap(map[string]sarama.MockResponse{
+				"MetadataRequest": sarama.NewMockMetadataResponse(t).
+					SetBroker(broker.Addr(), broker.BrokerID()).
+					SetLeader(tp.Topic, tp.Partition, broker.BrokerID()),
+				"FetchRequest": sarama.NewMockWrapper(res),
+			})
+			config := sarama.NewConfig()
+			config.Version = sarama.V2_0_0_0
+			config.Consumer.IsolationLevel = tt.isolation
+			client, err := sarama.NewClient([]string{broker.Addr()}, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			skipped, err := OffsetsSkipped(client, tp, tt.from, 10)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want an error %v", err, tt.err)
+			}
+			if skipped != tt.skipped {
+				t.Errorf("skipped = %v, want %v", skipped, tt.skipped)
+			}
+		})
+	}
+}
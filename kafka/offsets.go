@@ -0,0 +1,183 @@
+package kafka
+
+import (
+	"fmt"
+	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/log"
+	"go.uber.org/zap"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PartitionsAll selects every partition of a topic.
+const PartitionsAll = "all"
+
+// ParsePartitions parses all or a comma separated list of partitions,
+// all is returned as nil.
+func ParsePartitions(s string) ([]int32, error) {
+	if s == "" || s == PartitionsAll {
+		return nil, nil
+	}
+	var partitions []int32
+	for _, p := range strings.Split(s, ",") {
+		partition, err := strconv.ParseInt(strings.TrimSpace(p), 10, 32)
+		if err != nil || partition < 0 {
+			return nil, fmt.Errorf("invalid partition %q, partitions should be all or a list like 0,3,5", p)
+		}
+		partitions = append(partitions, int32(partition))
+	}
+	return partitions, nil
+}
+
+// ParseOffset parses oldest, newest, -2, -1 or an absolute offset.
+func ParseOffset(s string) (int64, error) {
+	switch strings.TrimSpace(s) {
+	case "oldest":
+		return sarama.OffsetOldest, nil
+	case "newest":
+		return sarama.OffsetNewest, nil
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || offset < sarama.OffsetOldest {
+		return 0, fmt.Errorf("invalid offset %q, should be oldest, newest or a non negative number", s)
+	}
+	return offset, nil
+}
+
+// StartOffsets holds the offset every partition starts consuming at.
+type StartOffsets struct {
+	Default    int64
+	Partitions map[int32]int64
+}
+
+// ParseStartOffsets parses a comma separated list of partition=offset pairs,
+// an entry without partition sets the offset of all other partitions,
+// e.g. 0=100,1=oldest or oldest,3=42.
+func ParseStartOffsets(s string) (StartOffsets, error) {
+	offsets := StartOffsets{Default: sarama.OffsetNewest, Partitions: map[int32]int64{}}
+	if s == "" {
+		return offsets, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		i := strings.Index(entry, "=")
+		if i < 0 {
+			offset, err := ParseOffset(entry)
+			if err != nil {
+				return offsets, err
+			}
+			offsets.Default = offset
+			continue
+		}
+		partition, err := strconv.ParseInt(strings.TrimSpace(entry[:i]), 10, 32)
+		if err != nil || partition < 0 {
+			return offsets, fmt.Errorf("invalid partition in %q, offsets should be like 0=100,1=oldest", entry)
+		}
+		offset, err := ParseOffset(entry[i+1:])
+		if err != nil {
+			return offsets, err
+		}
+		offsets.Partitions[int32(partition)] = offset
+	}
+	return offsets, nil
+}
+
+// Offset returns the start offset of a partition.
+func (o StartOffsets) Offset(partition int32) int64 {
+	if offset, ok := o.Partitions[partition]; ok {
+		return offset
+	}
+	return o.Default
+}
+
+// Resolve returns the start offset of every partition.
+func (o StartOffsets) Resolve(tps []TopicPartition) map[TopicPartition]int64 {
+	offsets := map[TopicPartition]int64{}
+	for _, tp := range tps {
+		offsets[tp] = o.Offset(tp.Partition)
+	}
+	return offsets
+}
+
+// ParseTime parses an RFC3339 time or a duration relative to now, such as
+// -15m for fifteen minutes ago.
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if d, err := time.ParseDuration(s); err == nil {
+			return now.Add(d), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, should be RFC3339 like 2021-02-04T09:00:00Z or relative like -15m", s)
+}
+
+// OffsetsForTime returns the offset of the first message at or after t for
+// every partition. Partitions without such a message start at their high
+// water mark, so they only get new messages.
+func OffsetsForTime(client sarama.Client, tps []TopicPartition, t time.Time) (map[TopicPartition]int64, error) {
+	offsets := map[TopicPartition]int64{}
+	ms := t.UnixNano() / int64(time.Millisecond)
+	for _, tp := range tps {
+		offset, err := client.GetOffset(tp.Topic, tp.Partition, ms)
+		if err != nil {
+			return nil, fmt.Errorf("offset for time of %s: %w", tp, err)
+		}
+		if offset < 0 {
+			if offset, err = client.GetOffset(tp.Topic, tp.Partition, sarama.OffsetNewest); err != nil {
+				return nil, fmt.Errorf("offset of %s: %w", tp, err)
+			}
+			log.Info("No message after the time, waiting for new ones", zap.String("topic", tp.Topic),
+				zap.Int32("partition", tp.Partition), zap.Int64("offset", offset))
+		}
+		offsets[tp] = offset
+	}
+	return offsets, nil
+}
+
+// TopicPartition identifies a single partition.
+type TopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+func (tp TopicPartition) String() string {
+	return fmt.Sprintf("%s/%d", tp.Topic, tp.Partition)
+}
+
+// ResolvePartitions returns the given partitions of every topic, checking that
+// they exist, or all partitions of every topic when partitions is nil.
+func ResolvePartitions(c sarama.Consumer, topics []string, partitions []int32) ([]TopicPartition, error) {
+	var tps []TopicPartition
+	for _, topic := range topics {
+		existing, err := c.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("topic %s: %w", topic, err)
+		}
+		if partitions == nil {
+			sort.Slice(existing, func(i, j int) bool { return existing[i] < existing[j] })
+			for _, p := range existing {
+				tps = append(tps, TopicPartition{Topic: topic, Partition: p})
+			}
+			continue
+		}
+		for _, p := range partitions {
+			if !containsPartition(existing, p) {
+				return nil, fmt.Errorf("partition %d of topic %s: %w", p, topic, sarama.ErrUnknownTopicOrPartition)
+			}
+			tps = append(tps, TopicPartition{Topic: topic, Partition: p})
+		}
+	}
+	return tps, nil
+}
+
+func containsPartition(partitions []int32, p int32) bool {
+	for _, partition := range partitions {
+		if partition == p {
+			return true
+		}
+	}
+	return false
+}
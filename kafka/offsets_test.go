@@ -0,0 +1,124 @@
+package kafka
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestParsePartitions(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []int32
+		err  string
+	}{
+		{"", nil, ""},
+		{"all", nil, ""},
+		{"0", []int32{0}, ""},
+		{"0,3,5", []int32{0, 3, 5}, ""},
+		{" 1 , 2 ", []int32{1, 2}, ""},
+		{"-1", nil, `invalid partition "-1"`},
+		{"1,x", nil, `invalid partition "x"`},
+		{"1,", nil, `invalid partition ""`},
+		{"2147483648", nil, `invalid partition "2147483648"`},
+		{"ALL", nil, `invalid partition "ALL"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePartitions(tt.in)
+			if !errorContains(err, tt.err) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStartOffsets(t *testing.T) {
+	tests := []struct {
+		in   string
+		want StartOffsets
+		err  string
+	}{
+		{"", StartOffsets{Default: sarama.OffsetNewest, Partitions: map[int32]int64{}}, ""},
+		{"oldest", StartOffsets{Default: sarama.OffsetOldest, Partitions: map[int32]int64{}}, ""},
+		{"42", StartOffsets{Default: 42, Partitions: map[int32]int64{}}, ""},
+		{"-2", StartOffsets{Default: sarama.OffsetOldest, Partitions: map[int32]int64{}}, ""},
+		{"0=100,1=oldest", StartOffsets{Default: sarama.OffsetNewest, Partitions: map[int32]int64{0: 100, 1: sarama.OffsetOldest}}, ""},
+		{"oldest, 3 = 42", StartOffsets{Default: sarama.OffsetOldest, Partitions: map[int32]int64{3: 42}}, ""},
+		{"0=1,0=2", StartOffsets{Default: sarama.OffsetNewest, Partitions: map[int32]int64{0: 2}}, ""},
+		{"first", StartOffsets{}, `invalid offset "first"`},
+		{"-3", StartOffsets{}, `invalid offset "-3"`},
+		{"x=1", StartOffsets{}, `invalid partition in "x=1"`},
+		{"-1=1", StartOffsets{}, `invalid partition in "-1=1"`},
+		{"0=", StartOffsets{}, `invalid offset ""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseStartOffsets(tt.in)
+			if !errorContains(err, tt.err) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartOffsetsResolve(t *testing.T) {
+	offsets, err := ParseStartOffsets("oldest,1=42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tps := []TopicPartition{{"t", 0}, {"t", 1}, {"u", 1}}
+	want := map[TopicPartition]int64{{"t", 0}: sarama.OffsetOldest, {"t", 1}: 42, {"u", 1}: 42}
+	if got := offsets.Resolve(tps); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2021, 2, 4, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+		err  bool
+	}{
+		{"2021-02-04T09:00:00Z", now, false},
+		{"2021-02-04T10:30:00+01:00", now.Add(30 * time.Minute), false},
+		{"2021-02-04T09:00:00.5Z", now.Add(500 * time.Millisecond), false},
+		{"-15m", now.Add(-15 * time.Minute), false},
+		{"+1h30m", now.Add(90 * time.Minute), false},
+		{"-0s", now, false},
+		{"15m", time.Time{}, true},
+		{"-15", time.Time{}, true},
+		{"2021-02-04", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseTime(tt.in, now)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want an error %v", err, tt.err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// errorContains reports whether err contains want, or is nil for an empty want.
+func errorContains(err error, want string) bool {
+	if want == "" {
+		return err == nil
+	}
+	return err != nil && strings.Contains(err.Error(), want)
+}
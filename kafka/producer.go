@@ -7,3 +7,15 @@ import (
 func NewProducer(addrs []string, config *sarama.Config) (sarama.SyncProducer, error){
 	return sarama.NewSyncProducer(addrs, config)
 }
+
+// NewProducerFromClient returns a producer sharing client, closing the
+// producer leaves the client open.
+func NewProducerFromClient(client sarama.Client) (sarama.SyncProducer, error) {
+	return sarama.NewSyncProducerFromClient(client)
+}
+
+// NewAsyncProducer returns a producer which sends in the background, its
+// Successes and Errors channels have to be read when they are enabled.
+func NewAsyncProducer(addrs []string, config *sarama.Config) (sarama.AsyncProducer, error) {
+	return sarama.NewAsyncProducer(addrs, config)
+}
@@ -0,0 +1,275 @@
+package kafka
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/Shopify/sarama"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clientProperties maps client properties onto sarama.Config. Names follow the
+// Java client where the setting maps cleanly, sarama only settings are named
+// after their field path.
+var clientProperties = map[string]func(c *sarama.Config, v string) error{
+	// java client names
+	"client.id": func(c *sarama.Config, v string) error {
+		c.ClientID = v
+		return nil
+	},
+	"client.rack": func(c *sarama.Config, v string) error {
+		c.RackID = v
+		return nil
+	},
+	"max.in.flight.requests.per.connection": intProperty(func(c *sarama.Config, v int) { c.Net.MaxOpenRequests = v }),
+	"request.timeout.ms": msProperty(func(c *sarama.Config, v time.Duration) {
+		c.Net.ReadTimeout = v
+		c.Net.WriteTimeout = v
+	}),
+	"socket.connection.setup.timeout.ms": msProperty(func(c *sarama.Config, v time.Duration) { c.Net.DialTimeout = v }),
+	"metadata.max.age.ms":                msProperty(func(c *sarama.Config, v time.Duration) { c.Metadata.RefreshFrequency = v }),
+	"retry.backoff.ms": msProperty(func(c *sarama.Config, v time.Duration) {
+		c.Admin.Retry.Backoff = v
+		c.Metadata.Retry.Backoff = v
+		c.Producer.Retry.Backoff = v
+		c.Consumer.Retry.Backoff = v
+	}),
+	"acks": func(c *sarama.Config, v string) error {
+		acks, err := ParseRequiredAcks(v)
+		c.Producer.RequiredAcks = acks
+		return err
+	},
+	"compression.type": func(c *sarama.Config, v string) error {
+		codec, err := ParseCompression(v)
+		c.Producer.Compression = codec
+		return err
+	},
+	"compression.level":  intProperty(func(c *sarama.Config, v int) { c.Producer.CompressionLevel = v }),
+	"enable.idempotence": boolProperty(func(c *sarama.Config, v bool) { c.Producer.Idempotent = v }),
+	"retries":            intProperty(func(c *sarama.Config, v int) { c.Producer.Retry.Max = v }),
+	"linger.ms":          msProperty(func(c *sarama.Config, v time.Duration) { c.Producer.Flush.Frequency = v }),
+	"batch.size":         intProperty(func(c *sarama.Config, v int) { c.Producer.Flush.Bytes = v }),
+	"max.request.size":   intProperty(func(c *sarama.Config, v int) { c.Producer.MaxMessageBytes = v }),
+	"fetch.min.bytes":    int32Property(func(c *sarama.Config, v int32) { c.Consumer.Fetch.Min = v }),
+	"fetch.max.bytes":    int32Property(func(c *sarama.Config, v int32) { c.Consumer.Fetch.Max = v }),
+	"fetch.max.wait.ms":  msProperty(func(c *sarama.Config, v time.Duration) { c.Consumer.MaxWaitTime = v }),
+	"max.partition.fetch.bytes": int32Property(func(c *sarama.Config, v int32) {
+		c.Consumer.Fetch.Default = v
+	}),
+	"session.timeout.ms":      msProperty(func(c *sarama.Config, v time.Duration) { c.Consumer.Group.Session.Timeout = v }),
+	"heartbeat.interval.ms":   msProperty(func(c *sarama.Config, v time.Duration) { c.Consumer.Group.Heartbeat.Interval = v }),
+	"max.poll.interval.ms":    msProperty(func(c *sarama.Config, v time.Duration) { c.Consumer.Group.Rebalance.Timeout = v }),
+	"enable.auto.commit":      boolProperty(func(c *sarama.Config, v bool) { c.Consumer.Offsets.AutoCommit.Enable = v }),
+	"auto.commit.interval.ms": msProperty(func(c *sarama.Config, v time.Duration) { c.Consumer.Offsets.AutoCommit.Interval = v }),
+	"auto.offset.reset": func(c *sarama.Config, v string) error {
+		switch v {
+		case "earliest":
+			c.Consumer.Offsets.Initial = sarama.OffsetOldest
+		case "latest":
+			c.Consumer.Offsets.Initial = sarama.OffsetNewest
+		default:
+			return errors.New("should be earliest or latest")
+		}
+		return nil
+	},
+	"isolation.level": func(c *sarama.Config, v string) error {
+		level, err := ParseIsolationLevel(v)
+		c.Consumer.IsolationLevel = level
+		return err
+	},
+	"partition.assignment.strategy": func(c *sarama.Config, v string) error {
+		strategy, err := ParseBalanceStrategy(v)
+		c.Consumer.Group.Rebalance.Strategy = strategy
+		return err
+	},
+
+	// sarama only settings
+	"channel.buffer.size":             intProperty(func(c *sarama.Config, v int) { c.ChannelBufferSize = v }),
+	"net.dial.timeout.ms":             msProperty(func(c *sarama.Config, v time.Duration) { c.Net.DialTimeout = v }),
+	"net.read.timeout.ms":             msProperty(func(c *sarama.Config, v time.Duration) { c.Net.ReadTimeout = v }),
+	"net.write.timeout.ms":            msProperty(func(c *sarama.Config, v time.Duration) { c.Net.WriteTimeout = v }),
+	"net.keep.alive.ms":               msProperty(func(c *sarama.Config, v time.Duration) { c.Net.KeepAlive = v }),
+	"metadata.retry.max":              intProperty(func(c *sarama.Config, v int) { c.Metadata.Retry.Max = v }),
+	"metadata.retry.backoff.ms":       msProperty(func(c *sarama.Config, v time.Duration) { c.Metadata.Retry.Backoff = v }),
+	"metadata.full":                   boolProperty(func(c *sarama.Config, v bool) { c.Metadata.Full = v }),
+	"metadata.timeout.ms":             msProperty(func(c *sarama.Config, v time.Duration) { c.Metadata.Timeout = v }),
+	"admin.timeout.ms":                msProperty(func(c *sarama.Config, v time.Duration) { c.Admin.Timeout = v }),
+	"admin.retry.max":                 intProperty(func(c *sarama.Config, v int) { c.Admin.Retry.Max = v }),
+	"producer.timeout.ms":             msProperty(func(c *sarama.Config, v time.Duration) { c.Producer.Timeout = v }),
+	"producer.flush.messages":         intProperty(func(c *sarama.Config, v int) { c.Producer.Flush.Messages = v }),
+	"producer.flush.max.messages":     intProperty(func(c *sarama.Config, v int) { c.Producer.Flush.MaxMessages = v }),
+	"consumer.max.processing.time.ms": msProperty(func(c *sarama.Config, v time.Duration) { c.Consumer.MaxProcessingTime = v }),
+	"consumer.rebalance.retry.max":    intProperty(func(c *sarama.Config, v int) { c.Consumer.Group.Rebalance.Retry.Max = v }),
+	"consumer.offsets.retention.ms":   msProperty(func(c *sarama.Config, v time.Duration) { c.Consumer.Offsets.Retention = v }),
+}
+
+// ClientPropertyNames returns all supported client properties in sorted order.
+func ClientPropertyNames() []string {
+	var names []string
+	for name := range clientProperties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyClientProperty sets a single key=value client property on config.
+func ApplyClientProperty(config *sarama.Config, key, value string) error {
+	apply, ok := clientProperties[key]
+	if !ok {
+		return fmt.Errorf("unknown client property %q, supported properties are: %s", key, strings.Join(ClientPropertyNames(), ", "))
+	}
+	if err := apply(config, strings.TrimSpace(value)); err != nil {
+		return fmt.Errorf("invalid value %q for client property %s: %s", value, key, err)
+	}
+	return nil
+}
+
+// ParseClientProperty splits a key=value pair.
+func ParseClientProperty(kv string) (string, string, error) {
+	i := strings.Index(kv, "=")
+	if i <= 0 {
+		return "", "", fmt.Errorf("client property should be key=value, got %q", kv)
+	}
+	return strings.TrimSpace(kv[:i]), kv[i+1:], nil
+}
+
+// ReadPropertiesFile reads a java style properties file, keys and values are
+// separated by '=' or ':', lines starting with '#' or '!' are comments.
+func ReadPropertiesFile(path string) ([][2]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var properties [][2]string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i <= 0 {
+			return nil, fmt.Errorf("%s:%d: property should be key=value", path, n)
+		}
+		properties = append(properties, [2]string{strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])})
+	}
+	return properties, scanner.Err()
+}
+
+// ParseRequiredAcks parses the java client acks values 0, 1 and all (or -1).
+func ParseRequiredAcks(v string) (sarama.RequiredAcks, error) {
+	switch v {
+	case "0":
+		return sarama.NoResponse, nil
+	case "1":
+		return sarama.WaitForLocal, nil
+	case "all", "-1":
+		return sarama.WaitForAll, nil
+	}
+	return sarama.WaitForAll, errors.New("should be 0, 1 or all")
+}
+
+// RequiredAcksName returns the name ParseRequiredAcks parses acks from.
+func RequiredAcksName(acks sarama.RequiredAcks) string {
+	switch acks {
+	case sarama.NoResponse:
+		return "0"
+	case sarama.WaitForLocal:
+		return "1"
+	case sarama.WaitForAll:
+		return "all"
+	}
+	return strconv.Itoa(int(acks))
+}
+
+// ParseCompression parses a compression codec name.
+func ParseCompression(v string) (sarama.CompressionCodec, error) {
+	switch v {
+	case "none":
+		return sarama.CompressionNone, nil
+	case "gzip":
+		return sarama.CompressionGZIP, nil
+	case "snappy":
+		return sarama.CompressionSnappy, nil
+	case "lz4":
+		return sarama.CompressionLZ4, nil
+	case "zstd":
+		return sarama.CompressionZSTD, nil
+	}
+	return sarama.CompressionNone, errors.New("should be none, gzip, snappy, lz4 or zstd")
+}
+
+// ParseIsolationLevel parses read_uncommitted or read_committed.
+func ParseIsolationLevel(v string) (sarama.IsolationLevel, error) {
+	switch v {
+	case "read_uncommitted":
+		return sarama.ReadUncommitted, nil
+	case "read_committed":
+		return sarama.ReadCommitted, nil
+	}
+	return sarama.ReadUncommitted, errors.New("should be read_uncommitted or read_committed")
+}
+
+// ParseBalanceStrategy parses a consumer group partition assignment strategy.
+func ParseBalanceStrategy(v string) (sarama.BalanceStrategy, error) {
+	switch v {
+	case "range":
+		return sarama.BalanceStrategyRange, nil
+	case "roundrobin":
+		return sarama.BalanceStrategyRoundRobin, nil
+	case "sticky":
+		return sarama.BalanceStrategySticky, nil
+	}
+	return sarama.BalanceStrategyRange, errors.New("should be range, roundrobin or sticky")
+}
+
+func intProperty(set func(c *sarama.Config, v int)) func(c *sarama.Config, v string) error {
+	return func(c *sarama.Config, v string) error {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.New("should be an integer")
+		}
+		set(c, i)
+		return nil
+	}
+}
+
+func int32Property(set func(c *sarama.Config, v int32)) func(c *sarama.Config, v string) error {
+	return func(c *sarama.Config, v string) error {
+		i, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return errors.New("should be a 32 bit integer")
+		}
+		set(c, int32(i))
+		return nil
+	}
+}
+
+func boolProperty(set func(c *sarama.Config, v bool)) func(c *sarama.Config, v string) error {
+	return func(c *sarama.Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return errors.New("should be true or false")
+		}
+		set(c, b)
+		return nil
+	}
+}
+
+func msProperty(set func(c *sarama.Config, v time.Duration)) func(c *sarama.Config, v string) error {
+	return func(c *sarama.Config, v string) error {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ms < 0 {
+			return errors.New("should be a non negative number of milliseconds")
+		}
+		set(c, time.Duration(ms)*time.Millisecond)
+		return nil
+	}
+}
@@ -0,0 +1,143 @@
+package kafka
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestApplyClientProperty(t *testing.T) {
+	tests := []struct {
+		key, value string
+		// get returns the config setting the property changes
+		get  func(c *sarama.Config) interface{}
+		want interface{}
+		err  string
+	}{
+		{"client.id", "cli", func(c *sarama.Config) interface{} { return c.ClientID }, "cli", ""},
+		{"client.id", " cli ", func(c *sarama.Config) interface{} { return c.ClientID }, "cli", ""},
+		{"max.in.flight.requests.per.connection", "1", func(c *sarama.Config) interface{} { return c.Net.MaxOpenRequests }, 1, ""},
+		{"request.timeout.ms", "1500", func(c *sarama.Config) interface{} { return c.Net.ReadTimeout }, 1500 * time.Millisecond, ""},
+		{"request.timeout.ms", "1500", func(c *sarama.Config) interface{} { return c.Net.WriteTimeout }, 1500 * time.Millisecond, ""},
+		{"retry.backoff.ms", "250", func(c *sarama.Config) interface{} { return c.Consumer.Retry.Backoff }, 250 * time.Millisecond, ""},
+		{"acks", "0", func(c *sarama.Config) interface{} { return c.Producer.RequiredAcks }, sarama.NoResponse, ""},
+		{"acks", "-1", func(c *sarama.Config) interface{} { return c.Producer.RequiredAcks }, sarama.WaitForAll, ""},
+		{"compression.type", "zstd", func(c *sarama.Config) interface{} { return c.Producer.Compression }, sarama.CompressionZSTD, ""},
+		{"enable.idempotence", "true", func(c *sarama.Config) interface{} { return c.Producer.Idempotent }, true, ""},
+		{"fetch.max.bytes", "1048576", func(c *sarama.Config) interface{} { return c.Consumer.Fetch.Max }, int32(1048576), ""},
+		{"auto.offset.reset", "earliest", func(c *sarama.Config) interface{} { return c.Consumer.Offsets.Initial }, sarama.OffsetOldest, ""},
+		{"isolation.level", "read_committed", func(c *sarama.Config) interface{} { return c.Consumer.IsolationLevel }, sarama.ReadCommitted, ""},
+		{"partition.assignment.strategy", "sticky", func(c *sarama.Config) interface{} { return c.Consumer.Group.Rebalance.Strategy }, sarama.BalanceStrategySticky, ""},
+		{"metadata.full", "false", func(c *sarama.Config) interface{} { return c.Metadata.Full }, false, ""},
+
+		{"linger", "5", nil, nil, `unknown client property "linger", supported properties are: acks, admin.retry.max,`},
+		{"retries", "three", nil, nil, `invalid value "three" for client property retries: should be an integer`},
+		{"fetch.min.bytes", "4294967296", nil, nil, "should be a 32 bit integer"},
+		{"enable.auto.commit", "yes", nil, nil, "should be true or false"},
+		{"linger.ms", "-1", nil, nil, "should be a non negative number of milliseconds"},
+		{"linger.ms", "1.5", nil, nil, "should be a non negative number of milliseconds"},
+		{"acks", "2", nil, nil, "should be 0, 1 or all"},
+		{"compression.type", "brotli", nil, nil, "should be none, gzip, snappy, lz4 or zstd"},
+		{"auto.offset.reset", "none", nil, nil, "should be earliest or latest"},
+		{"isolation.level", "serializable", nil, nil, "should be read_uncommitted or read_committed"},
+		{"partition.assignment.strategy", "cooperative-sticky", nil, nil, "should be range, roundrobin or sticky"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			c := sarama.NewConfig()
+			err := ApplyClientProperty(c, tt.key, tt.value)
+			if !errorContains(err, tt.err) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+			if tt.get == nil {
+				return
+			}
+			if got := tt.get(c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientPropertyNames(t *testing.T) {
+	names := ClientPropertyNames()
+	if len(names) != len(clientProperties) || !sort.StringsAreSorted(names) {
+		t.Errorf("names = %v, want all %d properties sorted", names, len(clientProperties))
+	}
+}
+
+func TestParseClientProperty(t *testing.T) {
+	tests := []struct {
+		in         string
+		key, value string
+		err        bool
+	}{
+		{"acks=all", "acks", "all", false},
+		{" client.id =a=b", "client.id", "a=b", false},
+		{"client.id=", "client.id", "", false},
+		{"=all", "", "", true},
+		{"acks", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			key, value, err := ParseClientProperty(tt.in)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want an error %v", err, tt.err)
+			}
+			if key != tt.key || value != tt.value {
+				t.Errorf("got %q=%q, want %q=%q", key, value, tt.key, tt.value)
+			}
+		})
+	}
+}
+
+func TestReadPropertiesFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	path := write("client.properties", "# producer settings\n! also a comment\n\nacks = all\nclient.id:cli\n  linger.ms=5  \nsasl.jaas.config=a=b\n")
+	got, err := ReadPropertiesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{{"acks", "all"}, {"client.id", "cli"}, {"linger.ms", "5"}, {"sasl.jaas.config", "a=b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	path = write("invalid.properties", "acks=all\nretries\n")
+	if _, err := ReadPropertiesFile(path); err == nil || err.Error() != path+":2: property should be key=value" {
+		t.Errorf("err = %v, want the line of the invalid property", err)
+	}
+	if _, err := ReadPropertiesFile(filepath.Join(dir, "missing.properties")); !os.IsNotExist(err) {
+		t.Errorf("err = %v, want not exist", err)
+	}
+}
+
+func TestRequiredAcksName(t *testing.T) {
+	for _, acks := range []sarama.RequiredAcks{sarama.NoResponse, sarama.WaitForLocal, sarama.WaitForAll} {
+		parsed, err := ParseRequiredAcks(RequiredAcksName(acks))
+		if err != nil || parsed != acks {
+			t.Errorf("acks %d parsed back as %d, %v", acks, parsed, err)
+		}
+	}
+	if name := RequiredAcksName(3); name != "3" {
+		t.Errorf("name of acks 3 = %q", name)
+	}
+}
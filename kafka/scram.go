@@ -0,0 +1,158 @@
+package kafka
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/Shopify/sarama"
+	"golang.org/x/crypto/pbkdf2"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// scramClient implements sarama.SCRAMClient following RFC 5802.
+// Usernames and passwords are used as given, without SASLprep normalization.
+type scramClient struct {
+	hashGen func() hash.Hash
+
+	user     string
+	password string
+	authzID  string
+
+	step            int
+	nonce           string
+	clientFirstBare string
+	serverSignature []byte
+	done            bool
+}
+
+func newSCRAMClientGenerator(mechanism sarama.SASLMechanism) func() sarama.SCRAMClient {
+	hashGen := sha256.New
+	if mechanism == sarama.SASLTypeSCRAMSHA512 {
+		hashGen = sha512.New
+	}
+	return func() sarama.SCRAMClient {
+		return &scramClient{hashGen: hashGen}
+	}
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	c.user = userName
+	c.password = password
+	c.authzID = authzID
+	c.nonce = base64.RawStdEncoding.EncodeToString(nonce)
+	c.step = 0
+	c.done = false
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	c.step++
+	switch c.step {
+	case 1:
+		c.clientFirstBare = fmt.Sprintf("n=%s,r=%s", scramEscape(c.user), c.nonce)
+		return c.gs2Header() + c.clientFirstBare, nil
+	case 2:
+		return c.clientFinal(challenge)
+	case 3:
+		c.done = true
+		return "", c.verifyServerFinal(challenge)
+	default:
+		return "", errors.New("scram: unexpected challenge after the conversation finished")
+	}
+}
+
+func (c *scramClient) Done() bool {
+	return c.done
+}
+
+func (c *scramClient) gs2Header() string {
+	if c.authzID == "" {
+		return "n,,"
+	}
+	return fmt.Sprintf("n,a=%s,", scramEscape(c.authzID))
+}
+
+func (c *scramClient) clientFinal(serverFirst string) (string, error) {
+	attrs := parseSCRAMAttributes(serverFirst)
+	if e, ok := attrs["e"]; ok {
+		return "", fmt.Errorf("scram: server error: %s", e)
+	}
+	serverNonce := attrs["r"]
+	if !strings.HasPrefix(serverNonce, c.nonce) {
+		return "", errors.New("scram: server nonce does not extend the client nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(attrs["s"])
+	if err != nil {
+		return "", fmt.Errorf("scram: invalid salt: %s", err)
+	}
+	iterations, err := strconv.Atoi(attrs["i"])
+	if err != nil || iterations <= 0 {
+		return "", fmt.Errorf("scram: invalid iteration count %q", attrs["i"])
+	}
+
+	saltedPassword := pbkdf2.Key([]byte(c.password), salt, iterations, c.hashGen().Size(), c.hashGen)
+	clientKey := c.hmac(saltedPassword, []byte("Client Key"))
+	h := c.hashGen()
+	h.Write(clientKey)
+	storedKey := h.Sum(nil)
+
+	clientFinalWithoutProof := fmt.Sprintf("c=%s,r=%s", base64.StdEncoding.EncodeToString([]byte(c.gs2Header())), serverNonce)
+	authMessage := []byte(c.clientFirstBare + "," + serverFirst + "," + clientFinalWithoutProof)
+
+	clientSignature := c.hmac(storedKey, authMessage)
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ clientSignature[i]
+	}
+	serverKey := c.hmac(saltedPassword, []byte("Server Key"))
+	c.serverSignature = c.hmac(serverKey, authMessage)
+
+	return clientFinalWithoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+func (c *scramClient) verifyServerFinal(serverFinal string) error {
+	attrs := parseSCRAMAttributes(serverFinal)
+	if e, ok := attrs["e"]; ok {
+		return fmt.Errorf("scram: server error: %s", e)
+	}
+	signature, err := base64.StdEncoding.DecodeString(attrs["v"])
+	if err != nil {
+		return fmt.Errorf("scram: invalid server signature: %s", err)
+	}
+	if !hmac.Equal(signature, c.serverSignature) {
+		return errors.New("scram: server signature mismatch")
+	}
+	return nil
+}
+
+func (c *scramClient) hmac(key, data []byte) []byte {
+	mac := hmac.New(c.hashGen, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func parseSCRAMAttributes(msg string) map[string]string {
+	attrs := map[string]string{}
+	for _, field := range strings.Split(msg, ",") {
+		if len(field) < 2 || field[1] != '=' {
+			continue
+		}
+		attrs[field[:1]] = field[2:]
+	}
+	return attrs
+}
+
+func scramEscape(s string) string {
+	s = strings.Replace(s, "=", "=3D", -1)
+	return strings.Replace(s, ",", "=2C", -1)
+}
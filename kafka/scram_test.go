@@ -0,0 +1,140 @@
+package kafka
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"hash"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+// scramVectors are the example conversations of RFC 5802 with SHA-1 and of
+// RFC 7677 with SHA-256, both for user "user" with password "pencil".
+var scramVectors = []struct {
+	name        string
+	hashGen     func() hash.Hash
+	nonce       string
+	clientFirst string
+	serverFirst string
+	clientFinal string
+	serverFinal string
+}{
+	{
+		name:        "RFC 5802 SCRAM-SHA-1",
+		hashGen:     sha1.New,
+		nonce:       "fyko+d2lbbFgONRv9qkxdawL",
+		clientFirst: "n,,n=user,r=fyko+d2lbbFgONRv9qkxdawL",
+		serverFirst: "r=fyko+d2lbbFgONRv9qkxdawL3rfcNHYJY1ZVvWVs7j,s=QSXCR+Q6sek8bf92,i=4096",
+		clientFinal: "c=biws,r=fyko+d2lbbFgONRv9qkxdawL3rfcNHYJY1ZVvWVs7j,p=v0X8v3Bz2T0CJGbJQyF0X+HI4Ts=",
+		serverFinal: "v=rmF9pqV8S7suAoZWja4dJRkFsKQ=",
+	},
+	{
+		name:        "RFC 7677 SCRAM-SHA-256",
+		hashGen:     sha256.New,
+		nonce:       "rOprNGfwEbeRWgbNEkqO",
+		clientFirst: "n,,n=user,r=rOprNGfwEbeRWgbNEkqO",
+		serverFirst: "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096",
+		clientFinal: "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=",
+		serverFinal: "v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=",
+	},
+}
+
+// beginSCRAM begins a conversation with the fixed nonce of a test vector.
+func beginSCRAM(t *testing.T, hashGen func() hash.Hash, user, authzID, nonce string) *scramClient {
+	t.Helper()
+	c := &scramClient{hashGen: hashGen}
+	if err := c.Begin(user, "pencil", authzID); err != nil {
+		t.Fatal(err)
+	}
+	c.nonce = nonce
+	return c
+}
+
+func TestSCRAMVectors(t *testing.T) {
+	for _, v := range scramVectors {
+		t.Run(v.name, func(t *testing.T) {
+			c := beginSCRAM(t, v.hashGen, "user", "", v.nonce)
+			steps := []struct{ challenge, response string }{
+				{"", v.clientFirst},
+				{v.serverFirst, v.clientFinal},
+				{v.serverFinal, ""},
+			}
+			for i, s := range steps {
+				if c.Done() {
+					t.Fatalf("done before step %d", i+1)
+				}
+				response, err := c.Step(s.challenge)
+				if err != nil {
+					t.Fatalf("step %d: %v", i+1, err)
+				}
+				if response != s.response {
+					t.Errorf("step %d = %q, want %q", i+1, response, s.response)
+				}
+			}
+			if !c.Done() {
+				t.Error("not done after the server final message")
+			}
+			if _, err := c.Step(""); err == nil {
+				t.Error("a step after the conversation finished should fail")
+			}
+		})
+	}
+}
+
+func TestSCRAMErrors(t *testing.T) {
+	v := scramVectors[1]
+	tests := []struct {
+		name        string
+		serverFirst string
+		serverFinal string
+		err         string
+	}{
+		{"server error first", "e=unknown-user", "", "scram: server error: unknown-user"},
+		{"foreign nonce", "r=someoneelse,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096", "", "does not extend the client nonce"},
+		{"invalid salt", strings.Replace(v.serverFirst, "s=W22ZaJ0SNY7soEsUEjb6gQ==", "s=%%%", 1), "", "scram: invalid salt"},
+		{"no iterations", strings.Replace(v.serverFirst, "i=4096", "i=0", 1), "", `invalid iteration count "0"`},
+		{"server error final", v.serverFirst, "e=invalid-proof", "scram: server error: invalid-proof"},
+		{"wrong signature", v.serverFirst, "v=rmF9pqV8S7suAoZWja4dJRkFsKQ=", "scram: server signature mismatch"},
+		{"invalid signature", v.serverFirst, "v=%%%", "scram: invalid server signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := beginSCRAM(t, v.hashGen, "user", "", v.nonce)
+			if _, err := c.Step(""); err != nil {
+				t.Fatal(err)
+			}
+			_, err := c.Step(tt.serverFirst)
+			if err == nil && tt.serverFinal != "" {
+				_, err = c.Step(tt.serverFinal)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSCRAMClientFirst(t *testing.T) {
+	c := beginSCRAM(t, sha256.New, "a=b,c", "admin,1", "nonce")
+	first, err := c.Step("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "n,a=admin=2C1,n=a=3Db=2Cc,r=nonce"; first != want {
+		t.Errorf("client first = %q, want %q", first, want)
+	}
+
+	// Begin draws a new nonce for every conversation
+	gen := newSCRAMClientGenerator(sarama.SASLTypeSCRAMSHA512)
+	a, b := gen().(*scramClient), gen().(*scramClient)
+	a.Begin("user", "pencil", "")
+	b.Begin("user", "pencil", "")
+	if a.nonce == "" || a.nonce == b.nonce {
+		t.Errorf("nonces %q and %q should differ", a.nonce, b.nonce)
+	}
+	if a.hashGen().Size() != 64 {
+		t.Errorf("SCRAM-SHA-512 hash size = %d", a.hashGen().Size())
+	}
+}
@@ -0,0 +1,48 @@
+package kafka
+
+import (
+	"github.com/Shopify/sarama"
+	"regexp"
+	"sort"
+)
+
+// internalTopics are the topics the brokers keep for themselves.
+var internalTopics = map[string]bool{
+	"__consumer_offsets":  true,
+	"__transaction_state": true,
+}
+
+// IsInternalTopic reports whether topic is one of the internal topics of the
+// brokers.
+func IsInternalTopic(topic string) bool {
+	return internalTopics[topic]
+}
+
+// CompileTopicPattern compiles a regular expression which has to match the
+// whole topic name, like the pattern subscriptions of the java client.
+func CompileTopicPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// MatchTopics refreshes the metadata and returns the sorted topics matching
+// pattern, internal topics only when internal is set.
+func MatchTopics(client sarama.Client, pattern *regexp.Regexp, internal bool) ([]string, error) {
+	if err := client.RefreshMetadata(); err != nil {
+		return nil, err
+	}
+	topics, err := client.Topics()
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for _, topic := range topics {
+		if IsInternalTopic(topic) && !internal {
+			continue
+		}
+		if pattern.MatchString(topic) {
+			matched = append(matched, topic)
+		}
+	}
+	sort.Strings(matched)
+	return matched, nil
+}
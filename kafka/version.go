@@ -0,0 +1,148 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Shopify/sarama"
+)
+
+// VersionAuto asks --kafka-version to detect the protocol version from the brokers.
+const VersionAuto = "auto"
+
+var apiKeyNames = map[int16]string{
+	0:  "Produce",
+	1:  "Fetch",
+	2:  "ListOffsets",
+	3:  "Metadata",
+	4:  "LeaderAndIsr",
+	5:  "StopReplica",
+	6:  "UpdateMetadata",
+	7:  "ControlledShutdown",
+	8:  "OffsetCommit",
+	9:  "OffsetFetch",
+	10: "FindCoordinator",
+	11: "JoinGroup",
+	12: "Heartbeat",
+	13: "LeaveGroup",
+	14: "SyncGroup",
+	15: "DescribeGroups",
+	16: "ListGroups",
+	17: "SaslHandshake",
+	18: "ApiVersions",
+	19: "CreateTopics",
+	20: "DeleteTopics",
+	21: "DeleteRecords",
+	22: "InitProducerId",
+	23: "OffsetForLeaderEpoch",
+	24: "AddPartitionsToTxn",
+	25: "AddOffsetsToTxn",
+	26: "EndTxn",
+	27: "WriteTxnMarkers",
+	28: "TxnOffsetCommit",
+	29: "DescribeAcls",
+	30: "CreateAcls",
+	31: "DeleteAcls",
+	32: "DescribeConfigs",
+	33: "AlterConfigs",
+	34: "AlterReplicaLogDirs",
+	35: "DescribeLogDirs",
+	36: "SaslAuthenticate",
+	37: "CreatePartitions",
+	38: "CreateDelegationToken",
+	39: "RenewDelegationToken",
+	40: "ExpireDelegationToken",
+	41: "DescribeDelegationToken",
+	42: "DeleteGroups",
+	43: "ElectLeaders",
+	44: "IncrementalAlterConfigs",
+	45: "AlterPartitionReassignments",
+	46: "ListPartitionReassignments",
+	47: "OffsetDelete",
+	48: "DescribeClientQuotas",
+	49: "AlterClientQuotas",
+	50: "DescribeUserScramCredentials",
+	51: "AlterUserScramCredentials",
+}
+
+// versionMarkers lists, in ascending order, an API version which first
+// appeared in the given Kafka release. The highest release whose marker the
+// broker supports is taken as the broker version.
+var versionMarkers = []struct {
+	version    sarama.KafkaVersion
+	apiKey     int16
+	maxVersion int16
+}{
+	{sarama.V0_10_0_0, 18, 0}, // ApiVersions
+	{sarama.V0_10_1_0, 19, 0}, // CreateTopics
+	{sarama.V0_10_2_0, 19, 1}, // CreateTopics v1
+	{sarama.V0_11_0_0, 22, 0}, // InitProducerId
+	{sarama.V1_0_0_0, 37, 0},  // CreatePartitions
+	{sarama.V1_1_0_0, 38, 0},  // CreateDelegationToken
+	{sarama.V2_0_0_0, 1, 8},   // Fetch v8
+	{sarama.V2_1_0_0, 1, 10},  // Fetch v10
+	{sarama.V2_2_0_0, 43, 0},  // ElectLeaders
+	{sarama.V2_3_0_0, 44, 0},  // IncrementalAlterConfigs
+	{sarama.V2_4_0_0, 45, 0},  // AlterPartitionReassignments
+	{sarama.V2_5_0_0, 28, 3},  // TxnOffsetCommit v3
+	{sarama.V2_6_0_0, 48, 0},  // DescribeClientQuotas
+}
+
+// ApiKeyName returns the protocol name of an api key.
+func ApiKeyName(key int16) string {
+	if name, ok := apiKeyNames[key]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(%d)", key)
+}
+
+// DetectVersion sends ApiVersions to the first reachable bootstrap broker and
+// returns the highest Kafka version supported by both the broker and sarama,
+// together with the api ranges the broker reported.
+func DetectVersion(addrs []string, config *sarama.Config) (sarama.KafkaVersion, []*sarama.ApiVersionsResponseBlock, error) {
+	var lastErr error
+	for _, addr := range addrs {
+		res, err := fetchApiVersions(addr, config)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return versionFromApiVersions(res.ApiVersions), res.ApiVersions, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no bootstrap servers")
+	}
+	return sarama.KafkaVersion{}, nil, fmt.Errorf("detect kafka version: %s", lastErr)
+}
+
+func fetchApiVersions(addr string, config *sarama.Config) (*sarama.ApiVersionsResponse, error) {
+	broker := sarama.NewBroker(addr)
+	if err := broker.Open(config); err != nil {
+		return nil, err
+	}
+	defer broker.Close()
+	res, err := broker.ApiVersions(&sarama.ApiVersionsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if res.Err != sarama.ErrNoError {
+		return nil, res.Err
+	}
+	return res, nil
+}
+
+func versionFromApiVersions(blocks []*sarama.ApiVersionsResponseBlock) sarama.KafkaVersion {
+	maxVersions := map[int16]int16{}
+	for _, b := range blocks {
+		maxVersions[b.ApiKey] = b.MaxVersion
+	}
+	version := sarama.V0_10_0_0
+	for _, m := range versionMarkers {
+		if max, ok := maxVersions[m.apiKey]; ok && max >= m.maxVersion {
+			version = m.version
+		}
+	}
+	if sarama.MaxVersion.IsAtLeast(version) {
+		return version
+	}
+	return sarama.MaxVersion
+}
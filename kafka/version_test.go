@@ -0,0 +1,99 @@
+package kafka
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// apiVersions returns the api ranges of a broker, key:max pairs.
+func apiVersions(maxVersions ...int16) []*sarama.ApiVersionsResponseBlock {
+	var blocks []*sarama.ApiVersionsResponseBlock
+	for i := 0; i < len(maxVersions); i += 2 {
+		blocks = append(blocks, &sarama.ApiVersionsResponseBlock{ApiKey: maxVersions[i], MaxVersion: maxVersions[i+1]})
+	}
+	return blocks
+}
+
+func TestVersionFromApiVersions(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks []*sarama.ApiVersionsResponseBlock
+		want   sarama.KafkaVersion
+	}{
+		{"no markers", nil, sarama.V0_10_0_0},
+		{"0.10.0", apiVersions(0, 2, 1, 2, 18, 0), sarama.V0_10_0_0},
+		{"0.10.1", apiVersions(1, 3, 18, 0, 19, 0), sarama.V0_10_1_0},
+		{"0.10.2", apiVersions(1, 3, 18, 0, 19, 1), sarama.V0_10_2_0},
+		{"0.11", apiVersions(1, 5, 18, 1, 19, 2, 22, 0), sarama.V0_11_0_0},
+		{"1.0", apiVersions(1, 6, 19, 2, 22, 0, 37, 0), sarama.V1_0_0_0},
+		{"1.1", apiVersions(1, 7, 37, 0, 38, 0), sarama.V1_1_0_0},
+		{"2.0", apiVersions(1, 8, 38, 1), sarama.V2_0_0_0},
+		{"2.1", apiVersions(1, 10, 38, 1), sarama.V2_1_0_0},
+		{"2.2", apiVersions(1, 10, 43, 0), sarama.V2_2_0_0},
+		{"2.3", apiVersions(1, 11, 43, 1, 44, 0), sarama.V2_3_0_0},
+		{"2.4", apiVersions(1, 11, 45, 0), sarama.V2_4_0_0},
+		{"2.5", apiVersions(1, 11, 28, 3, 45, 0), sarama.V2_5_0_0},
+		{"2.6", apiVersions(1, 11, 28, 3, 48, 0), sarama.V2_6_0_0},
+		// a newer broker gets the newest version sarama knows
+		{"newer than sarama", apiVersions(1, 12, 28, 3, 48, 1, 60, 0), sarama.MaxVersion},
+		// a marker counts only from its api version on
+		{"CreateTopics v0 only", apiVersions(19, 0, 28, 2), sarama.V0_10_1_0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := versionFromApiVersions(tt.blocks); got != tt.want {
+				t.Errorf("version = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectVersion(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockWrapper(&sarama.ApiVersionsResponse{ApiVersions: apiVersions(1, 7, 37, 0, 38, 0)}),
+	})
+	failing := sarama.NewMockBroker(t, 2)
+	defer failing.Close()
+	failing.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockWrapper(&sarama.ApiVersionsResponse{Err: sarama.ErrUnsupportedVersion}),
+	})
+	// a port which was just released does not answer
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := l.Addr().String()
+	l.Close()
+
+	config := sarama.NewConfig()
+	config.Net.DialTimeout = time.Second
+	// the brokers which do not answer are skipped
+	version, blocks, err := DetectVersion([]string{down, failing.Addr(), broker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != sarama.V1_1_0_0 || len(blocks) != 3 {
+		t.Errorf("version = %s with %d apis, want 1.1.0.0 with 3", version, len(blocks))
+	}
+
+	if _, _, err := DetectVersion([]string{down, failing.Addr()}, config); err == nil || err.Error() != "detect kafka version: "+sarama.ErrUnsupportedVersion.Error() {
+		t.Errorf("err = %v, want the error of the last broker", err)
+	}
+	if _, _, err := DetectVersion(nil, config); err == nil || err.Error() != "detect kafka version: no bootstrap servers" {
+		t.Errorf("err = %v", err)
+	}
+
+	// the detected version replaces auto in the config
+	o := &ClientOptions{BootstrapServers: broker.Addr(), KafkaVersion: VersionAuto}
+	if config, err = o.NewConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if config.Version != sarama.V1_1_0_0 {
+		t.Errorf("config version = %s, want 1.1.0.0", config.Version)
+	}
+}
@@ -0,0 +1,62 @@
+package perf
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// CSVWriter writes a time series of windows, one row per window, with the
+// latency columns when latency is set.
+type CSVWriter struct {
+	w       *csv.Writer
+	start   time.Time
+	latency bool
+}
+
+// NewCSVWriter writes the header, elapsed is counted from start.
+func NewCSVWriter(w io.Writer, start time.Time, latency bool) (*CSVWriter, error) {
+	c := &CSVWriter{w: csv.NewWriter(w), start: start, latency: latency}
+	header := []string{"time", "elapsed_sec", "records", "records_per_sec", "mb_per_sec", "errors"}
+	if latency {
+		header = append(header, "latency_p50_ms", "latency_p95_ms", "latency_p99_ms", "latency_max_ms")
+	}
+	if err := c.w.Write(header); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Write writes the row of a window and flushes it, so the series can be
+// followed while the run goes on.
+func (c *CSVWriter) Write(s Stats) error {
+	end := s.Start.Add(s.Elapsed)
+	row := []string{
+		end.Format(time.RFC3339Nano),
+		formatFloat(end.Sub(c.start).Seconds()),
+		strconv.FormatInt(s.Records, 10),
+		formatFloat(s.RecordsPerSec()),
+		formatFloat(s.MBPerSec()),
+		strconv.FormatInt(s.Errors, 10),
+	}
+	if c.latency {
+		for _, d := range []time.Duration{s.Latency.Quantile(0.5), s.Latency.Quantile(0.95), s.Latency.Quantile(0.99), s.Latency.Max()} {
+			row = append(row, formatFloat(Millis(d)))
+		}
+	}
+	if err := c.w.Write(row); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Millis returns d in milliseconds.
+func Millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
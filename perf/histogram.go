@@ -0,0 +1,129 @@
+package perf
+
+import (
+	"math/bits"
+	"time"
+)
+
+// subBuckets is the number of buckets every power of two is split into, so a
+// recorded latency is reported within 1/64, about 1.6%, of its value.
+const (
+	subBucketBits = 6
+	subBuckets    = 1 << subBucketBits
+)
+
+// Histogram counts latencies in microseconds in log linear buckets, it keeps
+// the exact maximum and the mean. It is not safe for concurrent use.
+type Histogram struct {
+	counts []int64
+	count  int64
+	sum    time.Duration
+	max    time.Duration
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{}
+}
+
+// bucket returns the index of the bucket of v, values below 2*subBuckets have
+// a bucket each.
+func bucket(v uint64) int {
+	if v < 2*subBuckets {
+		return int(v)
+	}
+	shift := bits.Len64(v) - 1 - subBucketBits
+	return shift*subBuckets + int(v>>uint(shift))
+}
+
+// bucketValue returns the highest value of the bucket with index i.
+func bucketValue(i int) uint64 {
+	if i < 2*subBuckets {
+		return uint64(i)
+	}
+	shift := i/subBuckets - 1
+	m := uint64(i - shift*subBuckets)
+	return (m+1)<<uint(shift) - 1
+}
+
+// Record counts a latency, negative latencies count as 0.
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	i := bucket(uint64(d / time.Microsecond))
+	if i >= len(h.counts) {
+		counts := make([]int64, i+1)
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+	h.counts[i]++
+	h.count++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// Merge adds the latencies of o.
+func (h *Histogram) Merge(o *Histogram) {
+	if len(o.counts) > len(h.counts) {
+		counts := make([]int64, len(o.counts))
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.count += o.count
+	h.sum += o.sum
+	if o.max > h.max {
+		h.max = o.max
+	}
+}
+
+// Reset forgets every latency.
+func (h *Histogram) Reset() {
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+	h.count, h.sum, h.max = 0, 0, 0
+}
+
+func (h *Histogram) Count() int64 {
+	return h.count
+}
+
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+// Quantile returns the latency below which the fraction q of the latencies
+// are, e.g. 0.99 for the 99th percentile, it is never above the maximum.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := int64(q*float64(h.count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			d := time.Duration(bucketValue(i)) * time.Microsecond
+			if d > h.max {
+				d = h.max
+			}
+			return d
+		}
+	}
+	return h.max
+}
@@ -0,0 +1,133 @@
+package perf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	tests := []struct {
+		v      uint64
+		bucket int
+		// high is the highest value of the bucket
+		high uint64
+	}{
+		{0, 0, 0},
+		{1, 1, 1},
+		{127, 127, 127},
+		{128, 128, 129},
+		{129, 128, 129},
+		{130, 129, 131},
+		{254, 191, 255},
+		{255, 191, 255},
+		{256, 192, 259},
+		{259, 192, 259},
+		{260, 193, 263},
+		{511, 255, 511},
+		{512, 256, 519},
+		{1 << 20, 14*subBuckets + subBuckets, 1<<20 + 1<<14 - 1},
+	}
+	for _, tt := range tests {
+		if got := bucket(tt.v); got != tt.bucket {
+			t.Errorf("bucket(%d) = %d, want %d", tt.v, got, tt.bucket)
+		}
+		if got := bucketValue(tt.bucket); got != tt.high {
+			t.Errorf("bucketValue(%d) = %d, want %d", tt.bucket, got, tt.high)
+		}
+	}
+}
+
+func TestBucketRoundTrip(t *testing.T) {
+	values := []uint64{1<<63 - 1, 1 << 62}
+	for shift := uint(0); shift < 40; shift++ {
+		for _, d := range []int64{-1, 0, 1} {
+			values = append(values, uint64(int64(1)<<shift+d))
+		}
+	}
+	for _, v := range values {
+		i := bucket(v)
+		high := bucketValue(i)
+		if high < v {
+			t.Errorf("%d is above the highest value %d of its bucket %d", v, high, i)
+		}
+		if i > 0 && bucketValue(i-1) >= v {
+			t.Errorf("%d is not above the highest value %d of the bucket %d before", v, bucketValue(i-1), i-1)
+		}
+		if bucket(high) != i {
+			t.Errorf("the highest value %d of bucket %d is in bucket %d", high, i, bucket(high))
+		}
+		// a value is reported within 1/subBuckets of itself
+		if float64(high-v) > float64(v)/subBuckets {
+			t.Errorf("%d is reported as %d", v, high)
+		}
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	h := NewHistogram()
+	if h.Quantile(0.5) != 0 || h.Mean() != 0 {
+		t.Error("an empty histogram should report 0")
+	}
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	h.Record(-time.Second)
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, 0},
+		{0.5, 50 * time.Millisecond},
+		{0.9, 90 * time.Millisecond},
+		{0.99, 99 * time.Millisecond},
+		// the bucket of 100ms ends above it, the maximum caps it
+		{1, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		got := h.Quantile(tt.q)
+		if got > h.Max() || got < tt.want || float64(got-tt.want) > float64(tt.want)/subBuckets {
+			t.Errorf("Quantile(%v) = %s, want %s within 1/%d", tt.q, got, tt.want, subBuckets)
+		}
+	}
+	if h.Count() != 101 || h.Max() != 100*time.Millisecond || h.Mean() != 5050*time.Millisecond/101 {
+		t.Errorf("count %d, max %s, mean %s", h.Count(), h.Max(), h.Mean())
+	}
+
+	// a single latency in the middle of a wide bucket
+	h = NewHistogram()
+	h.Record(1000003 * time.Microsecond)
+	if got := h.Quantile(0.99); got != 1000003*time.Microsecond {
+		t.Errorf("Quantile(0.99) = %s, want the maximum", got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	small, large := NewHistogram(), NewHistogram()
+	for i := 0; i < 90; i++ {
+		small.Record(time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		large.Record(time.Second)
+	}
+
+	// merging a histogram with more buckets grows the counts
+	merged := NewHistogram()
+	merged.Merge(small)
+	merged.Merge(large)
+	if merged.Count() != 100 || merged.Max() != time.Second || merged.Mean() != (90*time.Millisecond+10*time.Second)/100 {
+		t.Errorf("count %d, max %s, mean %s", merged.Count(), merged.Max(), merged.Mean())
+	}
+	// 1ms is reported as the highest value of its bucket
+	if p50, p95 := merged.Quantile(0.5), merged.Quantile(0.95); p50 != 1007*time.Microsecond || p95 != time.Second {
+		t.Errorf("p50 %s, p95 %s", p50, p95)
+	}
+	// the merged histograms are left alone
+	if small.Count() != 90 || large.Count() != 10 || small.Max() != time.Millisecond {
+		t.Error("merge changed its argument")
+	}
+
+	merged.Reset()
+	if merged.Count() != 0 || merged.Max() != 0 || merged.Quantile(0.5) != 0 {
+		t.Error("reset kept latencies")
+	}
+}
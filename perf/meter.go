@@ -0,0 +1,105 @@
+package perf
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats are the records, bytes and errors of a reporting window or of the
+// whole run, Latency is empty when no latencies are recorded.
+type Stats struct {
+	Start   time.Time
+	Elapsed time.Duration
+	Records int64
+	Bytes   int64
+	Errors  int64
+	Latency *Histogram
+}
+
+func (s Stats) RecordsPerSec() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Records) / s.Elapsed.Seconds()
+}
+
+func (s Stats) MBPerSec() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / (1 << 20) / s.Elapsed.Seconds()
+}
+
+// Meter counts the records of a run, for the reports at an interval and at
+// the end. It is safe for concurrent use.
+type Meter struct {
+	mu     sync.Mutex
+	total  Stats
+	window Stats
+}
+
+func NewMeter() *Meter {
+	now := time.Now()
+	return &Meter{
+		total:  Stats{Start: now, Latency: NewHistogram()},
+		window: Stats{Start: now, Latency: NewHistogram()},
+	}
+}
+
+// Record counts a record of bytes without a latency.
+func (m *Meter) Record(bytes int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.window.Records++
+	m.window.Bytes += int64(bytes)
+}
+
+// RecordLatency counts a record of bytes which took latency.
+func (m *Meter) RecordLatency(bytes int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.window.Records++
+	m.window.Bytes += int64(bytes)
+	m.window.Latency.Record(latency)
+}
+
+// Error counts a failed record.
+func (m *Meter) Error() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.window.Errors++
+}
+
+// Window returns the stats since the previous window and starts the next.
+func (m *Meter) Window() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.roll(time.Now())
+}
+
+// Total ends the current window and returns the stats of the whole run.
+func (m *Meter) Total() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.roll(now)
+	total := m.total
+	total.Elapsed = now.Sub(total.Start)
+	latency := NewHistogram()
+	latency.Merge(m.total.Latency)
+	total.Latency = latency
+	return total
+}
+
+// roll adds the current window to the total and starts the next one, the
+// returned window keeps its own histogram.
+func (m *Meter) roll(now time.Time) Stats {
+	w := m.window
+	w.Elapsed = now.Sub(w.Start)
+	m.total.Records += w.Records
+	m.total.Bytes += w.Bytes
+	m.total.Errors += w.Errors
+	m.total.Latency.Merge(w.Latency)
+	m.window = Stats{Start: now, Latency: NewHistogram()}
+	return w
+}
@@ -0,0 +1,54 @@
+package perf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsRates(t *testing.T) {
+	s := Stats{Elapsed: 2 * time.Second, Records: 1000, Bytes: 4 << 20}
+	if s.RecordsPerSec() != 500 || s.MBPerSec() != 2 {
+		t.Errorf("%v records/s, %v MB/s, want 500 and 2", s.RecordsPerSec(), s.MBPerSec())
+	}
+	if s := (Stats{Records: 10, Bytes: 10}); s.RecordsPerSec() != 0 || s.MBPerSec() != 0 {
+		t.Error("a window without elapsed time should have no rate")
+	}
+}
+
+func TestMeterWindows(t *testing.T) {
+	m := NewMeter()
+	m.RecordLatency(100, time.Millisecond)
+	m.RecordLatency(100, 3*time.Millisecond)
+	m.Record(50)
+	m.Error()
+
+	w := m.Window()
+	if w.Records != 3 || w.Bytes != 250 || w.Errors != 1 || w.Latency.Count() != 2 || w.Latency.Max() != 3*time.Millisecond {
+		t.Errorf("first window %+v with %d latencies", w, w.Latency.Count())
+	}
+	if w.Elapsed <= 0 {
+		t.Error("the window has no elapsed time")
+	}
+
+	// the next window starts empty
+	m.RecordLatency(10, 5*time.Millisecond)
+	if w := m.Window(); w.Records != 1 || w.Bytes != 10 || w.Errors != 0 || w.Latency.Count() != 1 || w.Latency.Max() != 5*time.Millisecond {
+		t.Errorf("second window %+v", w)
+	}
+
+	// the total holds every window, the current one too
+	m.Record(1)
+	total := m.Total()
+	if total.Records != 5 || total.Bytes != 261 || total.Errors != 1 || total.Latency.Count() != 3 || total.Latency.Max() != 5*time.Millisecond {
+		t.Errorf("total %+v with %d latencies", total, total.Latency.Count())
+	}
+	if total.Latency.Mean() != 3*time.Millisecond {
+		t.Errorf("mean latency %s, want 3ms", total.Latency.Mean())
+	}
+
+	// the returned total is a copy
+	total.Latency.Record(time.Hour)
+	if m.Total().Latency.Max() != 5*time.Millisecond {
+		t.Error("changing a returned total changed the meter")
+	}
+}
@@ -0,0 +1,90 @@
+package perf
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+)
+
+const (
+	PayloadRandom = "random"
+	PayloadFixed  = "fixed"
+)
+
+// randomPool is the size of the random bytes random payloads are cut from.
+const randomPool = 1 << 20
+
+// Payloads returns the values of the records of a run.
+type Payloads interface {
+	Next() []byte
+}
+
+// NewPayloads returns payloads of size bytes, random ones differ in every
+// record and do not compress, fixed ones are the same letters every time.
+func NewPayloads(kind string, size int, rnd *rand.Rand) (Payloads, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("the record size should not be negative")
+	}
+	switch kind {
+	case PayloadRandom:
+		pool := make([]byte, randomPool+size)
+		rnd.Read(pool)
+		return &randomPayloads{pool: pool, size: size, rnd: rnd}, nil
+	case PayloadFixed:
+		fixed := make([]byte, size)
+		for i := range fixed {
+			fixed[i] = byte('A' + i%26)
+		}
+		return fixedPayloads(fixed), nil
+	}
+	return nil, fmt.Errorf("unknown payload %q, should be %s or %s", kind, PayloadRandom, PayloadFixed)
+}
+
+// ReadPayloads returns payloads sampled from the non empty parts of a file
+// split at delimiter.
+func ReadPayloads(path, delimiter string, rnd *rand.Rand) (Payloads, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if delimiter == "" {
+		return nil, fmt.Errorf("empty payload delimiter")
+	}
+	var samples [][]byte
+	for _, s := range bytes.Split(data, []byte(delimiter)) {
+		if len(s) > 0 {
+			samples = append(samples, s)
+		}
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("%s has no payloads", path)
+	}
+	return &samplePayloads{samples: samples, rnd: rnd}, nil
+}
+
+type randomPayloads struct {
+	pool []byte
+	size int
+	rnd  *rand.Rand
+}
+
+func (p *randomPayloads) Next() []byte {
+	i := p.rnd.Intn(randomPool)
+	return p.pool[i : i+p.size]
+}
+
+type fixedPayloads []byte
+
+func (p fixedPayloads) Next() []byte {
+	return p
+}
+
+type samplePayloads struct {
+	samples [][]byte
+	rnd     *rand.Rand
+}
+
+func (p *samplePayloads) Next() []byte {
+	return p.samples[p.rnd.Intn(len(p.samples))]
+}
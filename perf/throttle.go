@@ -0,0 +1,41 @@
+package perf
+
+import (
+	"context"
+	"time"
+)
+
+// minSleep is the least a Throttle sleeps, shorter sleeps overshoot too much
+// to keep high rates.
+const minSleep = 2 * time.Millisecond
+
+// Throttle keeps a loop at a target rate per second, a target of 0 or less
+// does not throttle.
+type Throttle struct {
+	target float64
+	start  time.Time
+}
+
+func NewThrottle(target float64) *Throttle {
+	return &Throttle{target: target, start: time.Now()}
+}
+
+// Wait sleeps while done items are ahead of the target rate, it returns the
+// error of ctx when it is done first.
+func (t *Throttle) Wait(ctx context.Context, done int64) error {
+	if t.target <= 0 {
+		return nil
+	}
+	ahead := time.Duration(float64(done)/t.target*float64(time.Second)) - time.Since(t.start)
+	if ahead < minSleep {
+		return nil
+	}
+	timer := time.NewTimer(ahead)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
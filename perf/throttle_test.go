@@ -0,0 +1,54 @@
+package perf
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	tests := []struct {
+		name   string
+		target float64
+		// elapsed is the time since the throttle started
+		elapsed time.Duration
+		done    int64
+		// min and max bound the sleep
+		min, max time.Duration
+	}{
+		{"unthrottled", 0, 0, 1000000, 0, 10 * time.Millisecond},
+		{"negative target", -5, 0, 1000000, 0, 10 * time.Millisecond},
+		{"behind the target", 100, time.Second, 50, 0, 10 * time.Millisecond},
+		{"on target", 100, time.Second, 100, 0, 10 * time.Millisecond},
+		// sleeps shorter than minSleep are skipped
+		{"ahead less than the minimum sleep", 1000, time.Second, 1001, 0, 10 * time.Millisecond},
+		{"ahead", 100, time.Second, 110, 90 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := NewThrottle(tt.target)
+			th.start = time.Now().Add(-tt.elapsed)
+			start := time.Now()
+			if err := th.Wait(context.Background(), tt.done); err != nil {
+				t.Fatal(err)
+			}
+			if slept := time.Since(start); slept < tt.min || slept > tt.max {
+				t.Errorf("slept %s, want between %s and %s", slept, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestThrottleCanceled(t *testing.T) {
+	th := NewThrottle(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	// 60 records at 1 per second are a minute ahead
+	if err := th.Wait(ctx, 60); err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want the error of the context", err)
+	}
+	if slept := time.Since(start); slept > time.Second {
+		t.Errorf("slept %s after the context was done", slept)
+	}
+}
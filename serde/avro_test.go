@@ -0,0 +1,53 @@
+package serde
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAvroRoundTrip(t *testing.T) {
+	fake := newFakeRegistry(t)
+	r := NewRegistry(fake.URL, "", "")
+	e, err := NewAvroEncoder(r, -1, "users-value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.SchemaID() != 7 {
+		t.Fatalf("schema id = %d, want 7", e.SchemaID())
+	}
+	data, err := e.Encode(`{"id":42,"name":{"string":"Ada"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _, err := readWireHeader(data)
+	if err != nil || id != 7 {
+		t.Fatalf("wire header id = %d, %v, want 7", id, err)
+	}
+
+	v, err := newAvroDecoder(r).Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"id": int64(42), "name": map[string]interface{}{"string": "Ada"}}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("decoded %#v, want %#v", v, want)
+	}
+	if n := fake.requested(); n != 1 {
+		t.Errorf("got %d registry requests, want 1", n)
+	}
+}
+
+func TestAvroDecodeErrors(t *testing.T) {
+	fake := newFakeRegistry(t)
+	d := newAvroDecoder(NewRegistry(fake.URL, "", ""))
+	if _, err := d.Decode([]byte("plain")); err != ErrNotWireFormat {
+		t.Errorf("err = %v, want %v", err, ErrNotWireFormat)
+	}
+	if _, err := d.Decode(appendWireHeader(nil, 8)); err == nil {
+		t.Error("decoded with an unknown schema")
+	}
+	// a long needs more bytes than the truncated datum has
+	if _, err := d.Decode(append(appendWireHeader(nil, 7), 0x80)); err == nil {
+		t.Error("decoded a truncated datum")
+	}
+}
@@ -0,0 +1,190 @@
+package serde
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The formats which need no schema, they decode and encode symmetrically so
+// a consumed message can be produced back byte for byte.
+const (
+	FormatHex    = "hex"
+	FormatBase64 = "base64"
+	FormatInt32  = "int32"
+	FormatInt64  = "int64"
+	FormatUUID   = "uuid"
+	FormatDouble = "double"
+	FormatNull   = "null"
+)
+
+// PrimitiveFormats lists the formats which need no schema.
+var PrimitiveFormats = []string{FormatString, FormatHex, FormatBase64, FormatInt32, FormatInt64, FormatUUID, FormatDouble, FormatNull}
+
+// Format decodes and encodes a format which needs no schema.
+type Format interface {
+	Decoder
+	Encoder
+}
+
+var primitiveFormats = map[string]Format{
+	FormatString: stringFormat{},
+	FormatHex:    hexFormat{},
+	FormatBase64: base64Format{},
+	FormatInt32:  int32Format{},
+	FormatInt64:  int64Format{},
+	FormatUUID:   uuidFormat{},
+	FormatDouble: doubleFormat{},
+	FormatNull:   nullFormat{},
+}
+
+// NewFormat returns a format which needs no schema.
+func NewFormat(format string) (Format, error) {
+	if format == "" {
+		format = FormatString
+	}
+	f, ok := primitiveFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q, should be one of %s", format, strings.Join(PrimitiveFormats, ", "))
+	}
+	return f, nil
+}
+
+type stringFormat struct{}
+
+func (stringFormat) Decode(data []byte) (interface{}, error) {
+	return string(data), nil
+}
+
+func (stringFormat) Encode(text string) ([]byte, error) {
+	return []byte(text), nil
+}
+
+type hexFormat struct{}
+
+func (hexFormat) Decode(data []byte) (interface{}, error) {
+	return hex.EncodeToString(data), nil
+}
+
+func (hexFormat) Encode(text string) ([]byte, error) {
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex %q: %s", text, err)
+	}
+	return data, nil
+}
+
+type base64Format struct{}
+
+func (base64Format) Decode(data []byte) (interface{}, error) {
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func (base64Format) Encode(text string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 %q: %s", text, err)
+	}
+	return data, nil
+}
+
+// int32Format is a big endian signed 32 bit integer, like the IntegerSerializer of the java client.
+type int32Format struct{}
+
+func (int32Format) Decode(data []byte) (interface{}, error) {
+	if len(data) != 4 {
+		return nil, fmt.Errorf("int32 needs 4 bytes, got %d", len(data))
+	}
+	return int64(int32(binary.BigEndian.Uint32(data))), nil
+}
+
+func (int32Format) Encode(text string) ([]byte, error) {
+	i, err := strconv.ParseInt(strings.TrimSpace(text), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid int32 %q", text)
+	}
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, uint32(i))
+	return data, nil
+}
+
+// int64Format is a big endian signed 64 bit integer, like the LongSerializer of the java client.
+type int64Format struct{}
+
+func (int64Format) Decode(data []byte) (interface{}, error) {
+	if len(data) != 8 {
+		return nil, fmt.Errorf("int64 needs 8 bytes, got %d", len(data))
+	}
+	return int64(binary.BigEndian.Uint64(data)), nil
+}
+
+func (int64Format) Encode(text string) ([]byte, error) {
+	i, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid int64 %q", text)
+	}
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(i))
+	return data, nil
+}
+
+// uuidFormat is the 16 bytes of a uuid, written in its canonical text form.
+type uuidFormat struct{}
+
+func (uuidFormat) Decode(data []byte) (interface{}, error) {
+	if len(data) != 16 {
+		return nil, fmt.Errorf("uuid needs 16 bytes, got %d", len(data))
+	}
+	h := hex.EncodeToString(data)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+func (uuidFormat) Encode(text string) ([]byte, error) {
+	h := strings.Replace(strings.TrimSpace(text), "-", "", -1)
+	data, err := hex.DecodeString(h)
+	if err != nil || len(data) != 16 {
+		return nil, fmt.Errorf("invalid uuid %q", text)
+	}
+	return data, nil
+}
+
+// doubleFormat is a big endian IEEE 754 double, like the DoubleSerializer of the java client.
+type doubleFormat struct{}
+
+func (doubleFormat) Decode(data []byte) (interface{}, error) {
+	if len(data) != 8 {
+		return nil, fmt.Errorf("double needs 8 bytes, got %d", len(data))
+	}
+	f := math.Float64frombits(binary.BigEndian.Uint64(data))
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		// json has no such numbers, the text still parses back
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	}
+	return f, nil
+}
+
+func (doubleFormat) Encode(text string) ([]byte, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid double %q", text)
+	}
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, math.Float64bits(f))
+	return data, nil
+}
+
+// nullFormat ignores the data, it consumes and produces null keys and
+// values, the latter are tombstones of compacted topics.
+type nullFormat struct{}
+
+func (nullFormat) Decode([]byte) (interface{}, error) {
+	return nil, nil
+}
+
+func (nullFormat) Encode(string) ([]byte, error) {
+	return nil, nil
+}
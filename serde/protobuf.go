@@ -0,0 +1,176 @@
+package serde
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"io/ioutil"
+)
+
+// LoadDescriptorSet reads a FileDescriptorSet as written by
+// protoc --include_imports --descriptor_set_out.
+func LoadDescriptorSet(path string) (*protoregistry.Files, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("%s is not a protobuf descriptor set: %s", path, err)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s, was it built with --include_imports?", path, err)
+	}
+	return files, nil
+}
+
+func findMessage(files *protoregistry.Files, name string) (protoreflect.MessageDescriptor, error) {
+	if files == nil {
+		return nil, errors.New("the protobuf format needs --proto-descriptor-set")
+	}
+	if name == "" {
+		return nil, errors.New("the protobuf format needs the message type, e.g. --proto-message=shop.v1.Order")
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("message %s not found in the descriptor set", name)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message", name)
+	}
+	return md, nil
+}
+
+var protoJSON = protojson.MarshalOptions{UseProtoNames: true}
+
+type protoDecoder struct {
+	desc protoreflect.MessageDescriptor
+}
+
+// Decode renders the message as its protobuf json mapping with the field
+// names of the .proto file. Data in the Confluent wire format is detected by
+// its leading zero byte, which no protobuf message starts with, the schema id
+// is skipped and the message indexes pick the message type within the file
+// of the configured message.
+func (d *protoDecoder) Decode(data []byte) (interface{}, error) {
+	desc := d.desc
+	if len(data) > 0 && data[0] == wireMagic {
+		_, payload, err := readWireHeader(data)
+		if err != nil {
+			return nil, err
+		}
+		var indexes []int
+		if indexes, data, err = readMessageIndexes(payload); err != nil {
+			return nil, err
+		}
+		if desc, err = messageByIndexes(d.desc.ParentFile(), indexes); err != nil {
+			return nil, err
+		}
+	}
+	msg := dynamicpb.NewMessage(desc)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("protobuf %s: %s", desc.FullName(), err)
+	}
+	text, err := protoJSON.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON(text)
+}
+
+// ProtoEncoder encodes the protobuf json mapping of a message, in the
+// Confluent wire format when it has a schema id.
+type ProtoEncoder struct {
+	desc protoreflect.MessageDescriptor
+	id   int
+}
+
+// NewProtoEncoder returns an encoder of the named message, id is the
+// registered schema written in the wire format header, plain protobuf is
+// written when it is negative.
+func NewProtoEncoder(files *protoregistry.Files, message string, id int) (*ProtoEncoder, error) {
+	desc, err := findMessage(files, message)
+	if err != nil {
+		return nil, err
+	}
+	return &ProtoEncoder{desc: desc, id: id}, nil
+}
+
+func (e *ProtoEncoder) Encode(text string) ([]byte, error) {
+	msg := dynamicpb.NewMessage(e.desc)
+	if err := protojson.Unmarshal([]byte(text), msg); err != nil {
+		return nil, fmt.Errorf("protobuf %s: %s", e.desc.FullName(), err)
+	}
+	var buf []byte
+	if e.id >= 0 {
+		buf = appendMessageIndexes(appendWireHeader(nil, e.id), e.desc)
+	}
+	return proto.MarshalOptions{}.MarshalAppend(buf, msg)
+}
+
+// readMessageIndexes reads the path of the message type within its file,
+// a zigzag varint count followed by as many indexes, a zero count stands
+// for the first message.
+func readMessageIndexes(data []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 || count < 0 || count > int64(len(data)) {
+		return nil, nil, errors.New("invalid message indexes of the wire format")
+	}
+	data = data[n:]
+	if count == 0 {
+		return []int{0}, data, nil
+	}
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, n := binary.Varint(data)
+		if n <= 0 || index < 0 {
+			return nil, nil, errors.New("invalid message indexes of the wire format")
+		}
+		indexes[i] = int(index)
+		data = data[n:]
+	}
+	return indexes, data, nil
+}
+
+// messageByIndexes returns the message type at the path of message indexes
+// within file, the reverse of appendMessageIndexes.
+func messageByIndexes(file protoreflect.FileDescriptor, indexes []int) (protoreflect.MessageDescriptor, error) {
+	var desc protoreflect.MessageDescriptor
+	messages := file.Messages()
+	for _, i := range indexes {
+		if i >= messages.Len() {
+			return nil, fmt.Errorf("message indexes %v of the wire format are not in %s, is the record of another schema?", indexes, file.Path())
+		}
+		desc = messages.Get(i)
+		messages = desc.Messages()
+	}
+	return desc, nil
+}
+
+func appendMessageIndexes(dst []byte, desc protoreflect.MessageDescriptor) []byte {
+	var indexes []int
+	for d := protoreflect.Descriptor(desc); ; d = d.Parent() {
+		if _, ok := d.(protoreflect.FileDescriptor); ok {
+			break
+		}
+		indexes = append([]int{d.Index()}, indexes...)
+	}
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return append(dst, 0)
+	}
+	var buf [binary.MaxVarintLen64]byte
+	dst = append(dst, buf[:binary.PutVarint(buf[:], int64(len(indexes)))]...)
+	for _, i := range indexes {
+		dst = append(dst, buf[:binary.PutVarint(buf[:], int64(i))]...)
+	}
+	return dst
+}
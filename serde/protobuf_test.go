@@ -0,0 +1,92 @@
+package serde
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// testProtoFiles holds shop.proto with the messages Order, at index 0, and
+// Customer with the nested Address, at 1 and 1.0.
+func testProtoFiles(t *testing.T) *protoregistry.Files {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number),
+			Type: typ.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()}
+	}
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("shop.proto"),
+		Package: proto.String("shop"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Order"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			}},
+			{Name: proto.String("Customer"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			}, NestedType: []*descriptorpb.DescriptorProto{
+				{Name: proto.String("Address"), Field: []*descriptorpb.FieldDescriptorProto{
+					field("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				}},
+			}},
+		},
+	}
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestProtoMessageIndexes(t *testing.T) {
+	files := testProtoFiles(t)
+	d, err := NewDecoder(FormatProtobuf, "shop.Order", &Config{ProtoFiles: files})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		message string
+		text    string
+		want    map[string]interface{}
+	}{
+		{"shop.Order", `{"id":"o-1"}`, map[string]interface{}{"id": "o-1"}},
+		{"shop.Customer", `{"name":"Ada"}`, map[string]interface{}{"name": "Ada"}},
+		{"shop.Customer.Address", `{"city":"Lisbon"}`, map[string]interface{}{"city": "Lisbon"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			e, err := NewProtoEncoder(files, tt.message, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := e.Encode(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the decoder of shop.Order follows the indexes to the encoded message
+			v, err := d.Decode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(v, tt.want) {
+				t.Errorf("decoded %#v, want %#v", v, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtoMessageIndexesNotInFile(t *testing.T) {
+	d, err := NewDecoder(FormatProtobuf, "shop.Order", &Config{ProtoFiles: testProtoFiles(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// indexes [2], a third message which shop.proto does not have
+	data := append(appendWireHeader(nil, 3), 2, 4)
+	if _, err := d.Decode(data); err == nil || !strings.Contains(err.Error(), "are not in shop.proto") {
+		t.Errorf("err = %v, want the indexes not to be found", err)
+	}
+}
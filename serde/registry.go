@@ -0,0 +1,130 @@
+package serde
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schema types of the registry, an empty type means avro.
+const (
+	SchemaAvro     = "AVRO"
+	SchemaProtobuf = "PROTOBUF"
+	SchemaJSON     = "JSON"
+)
+
+// Schema is a schema registered in the Schema Registry.
+type Schema struct {
+	ID      int    `json:"id"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+	Type    string `json:"schemaType"`
+	Schema  string `json:"schema"`
+}
+
+// Registry is a client of the Confluent Schema Registry, schemas are cached
+// for the lifetime of the client.
+type Registry struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+
+	mu       sync.Mutex
+	byID     map[int]*Schema
+	subjects map[string]*Schema
+}
+
+func NewRegistry(registryURL, username, password string) *Registry {
+	return &Registry{
+		url:      strings.TrimSuffix(registryURL, "/"),
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		byID:     map[int]*Schema{},
+		subjects: map[string]*Schema{},
+	}
+}
+
+// SchemaByID returns the schema with the given id.
+func (r *Registry) SchemaByID(id int) (*Schema, error) {
+	r.mu.Lock()
+	s, ok := r.byID[id]
+	r.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+	s = &Schema{}
+	if err := r.get(fmt.Sprintf("/schemas/ids/%d", id), s); err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+	s.ID = id
+	r.mu.Lock()
+	r.byID[id] = s
+	r.mu.Unlock()
+	return s, nil
+}
+
+// LatestSchema returns the latest version of the schema of a subject.
+func (r *Registry) LatestSchema(subject string) (*Schema, error) {
+	r.mu.Lock()
+	s, ok := r.subjects[subject]
+	r.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+	s = &Schema{}
+	if err := r.get("/subjects/"+url.PathEscape(subject)+"/versions/latest", s); err != nil {
+		return nil, fmt.Errorf("subject %s: %w", subject, err)
+	}
+	r.mu.Lock()
+	r.subjects[subject] = s
+	r.byID[s.ID] = s
+	r.mu.Unlock()
+	return s, nil
+}
+
+// RegistryError is an error answer of the Schema Registry.
+type RegistryError struct {
+	StatusCode int
+	Code       int    `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+func (e *RegistryError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("schema registry answered %d", e.StatusCode)
+	}
+	return fmt.Sprintf("schema registry: %s (%d)", e.Message, e.Code)
+}
+
+func (r *Registry) get(path string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, r.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		e := &RegistryError{StatusCode: res.StatusCode}
+		json.Unmarshal(body, e)
+		return e
+	}
+	return json.Unmarshal(body, v)
+}
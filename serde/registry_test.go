@@ -0,0 +1,121 @@
+package serde
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+const userSchema = `{"type":"record","name":"User","fields":[
+	{"name":"id","type":"long"},
+	{"name":"name","type":["null","string"],"default":null}
+]}`
+
+// fakeRegistry serves schema 7 as version 3 of subject users-value and
+// counts the requests it got.
+type fakeRegistry struct {
+	*httptest.Server
+	requests int32
+	// username and password are required when set
+	username, password string
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	r := &fakeRegistry{}
+	schema := Schema{ID: 7, Subject: "users-value", Version: 3, Schema: userSchema}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&r.requests, 1)
+		if r.username != "" {
+			if username, password, ok := req.BasicAuth(); !ok || username != r.username || password != r.password {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error_code":401,"message":"Unauthorized"}`))
+				return
+			}
+		}
+		switch req.URL.Path {
+		case "/schemas/ids/7":
+			json.NewEncoder(w).Encode(map[string]string{"schema": schema.Schema})
+		case "/subjects/users-value/versions/latest":
+			json.NewEncoder(w).Encode(schema)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40403,"message":"Schema not found"}`))
+		}
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *fakeRegistry) requested() int {
+	return int(atomic.LoadInt32(&r.requests))
+}
+
+func TestRegistrySchemaByID(t *testing.T) {
+	fake := newFakeRegistry(t)
+	r := NewRegistry(fake.URL+"/", "", "")
+	for i := 0; i < 2; i++ {
+		s, err := r.SchemaByID(7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.ID != 7 || s.Schema != userSchema {
+			t.Errorf("schema = %+v", s)
+		}
+	}
+	if n := fake.requested(); n != 1 {
+		t.Errorf("got %d requests, want 1, the second lookup should be cached", n)
+	}
+}
+
+func TestRegistryLatestSchema(t *testing.T) {
+	fake := newFakeRegistry(t)
+	r := NewRegistry(fake.URL, "", "")
+	s, err := r.LatestSchema("users-value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != 7 || s.Subject != "users-value" || s.Version != 3 || s.Schema != userSchema {
+		t.Errorf("schema = %+v", s)
+	}
+	// the subject lookup caches the schema by id too
+	if _, err := r.LatestSchema("users-value"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.SchemaByID(7); err != nil {
+		t.Fatal(err)
+	}
+	if n := fake.requested(); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestRegistryBasicAuth(t *testing.T) {
+	fake := newFakeRegistry(t)
+	fake.username, fake.password = "alice", "secret"
+	if _, err := NewRegistry(fake.URL, "alice", "secret").SchemaByID(7); err != nil {
+		t.Fatal(err)
+	}
+	_, err := NewRegistry(fake.URL, "alice", "wrong").SchemaByID(7)
+	var registryErr *RegistryError
+	if !errors.As(err, &registryErr) || registryErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("err = %v, want a 401 registry error", err)
+	}
+}
+
+func TestRegistryNotFound(t *testing.T) {
+	fake := newFakeRegistry(t)
+	_, err := NewRegistry(fake.URL, "", "").SchemaByID(8)
+	var registryErr *RegistryError
+	if !errors.As(err, &registryErr) {
+		t.Fatalf("err = %v, want a registry error", err)
+	}
+	if registryErr.StatusCode != http.StatusNotFound || registryErr.Code != 40403 {
+		t.Errorf("err = %+v", registryErr)
+	}
+	if want := "schema 8: schema registry: Schema not found (40403)"; err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,193 @@
+// Package serde decodes consumed keys and values into something printable
+// and encodes the input of the producer, as plain strings or in the schema
+// based avro and protobuf formats.
+package serde
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Shopify/sarama"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"strconv"
+	"strings"
+)
+
+// The formats of keys and values, see also the formats which need no schema.
+const (
+	FormatString   = "string"
+	FormatAvro     = "avro"
+	FormatProtobuf = "protobuf"
+)
+
+// Formats lists all formats for flag help and errors.
+var Formats = append(append([]string{}, PrimitiveFormats...), FormatAvro, FormatProtobuf)
+
+// Decoder decodes the raw bytes of a key or value. Text formats return a
+// string, schema based formats a json compatible value of maps, slices,
+// strings, numbers and booleans.
+type Decoder interface {
+	Decode(data []byte) (interface{}, error)
+}
+
+// Encoder encodes the text given to the producer.
+type Encoder interface {
+	Encode(text string) ([]byte, error)
+}
+
+// Config holds what the schema based formats need, avro needs the registry
+// and protobuf the descriptor set and message types.
+type Config struct {
+	Registry          *Registry
+	ProtoFiles        *protoregistry.Files
+	ProtoKeyMessage   string
+	ProtoValueMessage string
+}
+
+// NewDecoder returns the decoder of a format, protoMessage is the message
+// type of the protobuf format.
+func NewDecoder(format, protoMessage string, c *Config) (Decoder, error) {
+	if f, ok := primitiveFormats[format]; ok {
+		return f, nil
+	}
+	switch format {
+	case "":
+		return stringFormat{}, nil
+	case FormatAvro:
+		if c.Registry == nil {
+			return nil, errors.New("the avro format needs --schema-registry-url")
+		}
+		return newAvroDecoder(c.Registry), nil
+	case FormatProtobuf:
+		desc, err := findMessage(c.ProtoFiles, protoMessage)
+		if err != nil {
+			return nil, err
+		}
+		return &protoDecoder{desc: desc}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q, should be one of %s", format, strings.Join(Formats, ", "))
+}
+
+// Message is a consumed message with its key, value and header values
+// decoded, nil stays nil.
+type Message struct {
+	Raw     *sarama.ConsumerMessage
+	Key     interface{}
+	Value   interface{}
+	Headers []Header
+}
+
+// Header is a decoded header.
+type Header struct {
+	Key   string
+	Value interface{}
+}
+
+// Decoders decode the keys, values and header values of consumed messages.
+type Decoders struct {
+	Key    Decoder
+	Value  Decoder
+	Header Decoder
+}
+
+// NewDecoders returns the decoders of the key, value and header formats.
+func NewDecoders(keyFormat, valueFormat, headerFormat string, c *Config) (*Decoders, error) {
+	key, err := NewDecoder(keyFormat, c.ProtoKeyMessage, c)
+	if err != nil {
+		return nil, fmt.Errorf("key format: %w", err)
+	}
+	value, err := NewDecoder(valueFormat, c.ProtoValueMessage, c)
+	if err != nil {
+		return nil, fmt.Errorf("value format: %w", err)
+	}
+	header, err := NewFormat(headerFormat)
+	if err != nil {
+		return nil, fmt.Errorf("header format: %w", err)
+	}
+	return &Decoders{Key: key, Value: value, Header: header}, nil
+}
+
+// Decode decodes the key and value of msg.
+func (d *Decoders) Decode(msg *sarama.ConsumerMessage) (*Message, error) {
+	m := &Message{Raw: msg}
+	var err error
+	if msg.Key != nil {
+		if m.Key, err = d.Key.Decode(msg.Key); err != nil {
+			return nil, fmt.Errorf("decode key: %w", err)
+		}
+	}
+	if msg.Value != nil {
+		if m.Value, err = d.Value.Decode(msg.Value); err != nil {
+			return nil, fmt.Errorf("decode value: %w", err)
+		}
+	}
+	for _, h := range msg.Headers {
+		header := Header{Key: string(h.Key)}
+		if h.Value != nil {
+			if header.Value, err = d.Header.Decode(h.Value); err != nil {
+				return nil, fmt.Errorf("decode header %s: %w", h.Key, err)
+			}
+		}
+		m.Headers = append(m.Headers, header)
+	}
+	return m, nil
+}
+
+// The Confluent wire format prefixes the payload with a zero magic byte and
+// the schema id as a big endian int32.
+const (
+	wireMagic      = 0
+	wireHeaderSize = 5
+)
+
+// ErrNotWireFormat is returned for data which does not start with the header
+// of the Confluent wire format.
+var ErrNotWireFormat = errors.New("not in the schema registry wire format")
+
+func readWireHeader(data []byte) (int, []byte, error) {
+	if len(data) < wireHeaderSize || data[0] != wireMagic {
+		return 0, nil, ErrNotWireFormat
+	}
+	return int(binary.BigEndian.Uint32(data[1:wireHeaderSize])), data[wireHeaderSize:], nil
+}
+
+func appendWireHeader(dst []byte, id int) []byte {
+	var header [wireHeaderSize]byte
+	header[0] = wireMagic
+	binary.BigEndian.PutUint32(header[1:], uint32(id))
+	return append(dst, header[:]...)
+}
+
+// decodeJSON decodes a json document keeping integers exact, they become
+// int64 while other numbers become float64.
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(v), nil
+}
+
+func normalizeNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, x := range v {
+			v[k] = normalizeNumbers(x)
+		}
+	case []interface{}:
+		for i, x := range v {
+			v[i] = normalizeNumbers(x)
+		}
+	}
+	return v
+}
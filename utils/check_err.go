@@ -3,25 +3,26 @@ package utils
 import (
 	"github.com/thimico/kafka-cli/log"
 	"go.uber.org/zap"
-	"os"
+	"io"
 )
 
 const (
 	DefaultErrorExitCode = 1
 )
 
-func fatal(msg string, code int) {
-	os.Exit(code)
-}
-
-func checkErr(err error, handleErr func(string, int)) {
-	if err == nil {
-		return
+// LogError logs err with its kind and hint.
+func LogError(err error) {
+	e := Classify(err)
+	fields := []zap.Field{zap.Error(e.Err), zap.String("kind", e.Kind.String()), zap.Int("exitCode", e.Kind.ExitCode())}
+	if e.Hint != "" {
+		fields = append(fields, zap.String("hint", e.Hint))
 	}
-	log.Error("checkErr", zap.Error(err))
-	handleErr(err.Error(), DefaultErrorExitCode)
+	log.Error("command failed", fields...)
 }
 
-func CheckErr(err error) {
-	checkErr(err, fatal)
+// Close closes c, its error is kept in err unless err already holds one.
+func Close(c io.Closer, err *error) {
+	if cerr := c.Close(); cerr != nil && *err == nil {
+		*err = cerr
+	}
 }
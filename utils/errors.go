@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/Shopify/sarama"
+	"io"
+	"net"
+	"strings"
+)
+
+/*
+Exit codes of kafka-cli:
+	0  success
+	1  any other failure
+	2  usage: invalid flags or arguments
+	3  connectivity: brokers unreachable, dns, tcp or tls failures
+	4  auth: authentication or authorization failed
+	5  not found: topic, partition, group or context does not exist
+	6  already exists: topic or group already exists
+	7  kafka: the broker answered with another error code
+*/
+
+type ErrorKind int
+
+const (
+	KindGeneral ErrorKind = iota
+	KindUsage
+	KindConnectivity
+	KindAuth
+	KindNotFound
+	KindAlreadyExists
+	KindKafka
+)
+
+var kindNames = map[ErrorKind]string{
+	KindGeneral:       "general",
+	KindUsage:         "usage",
+	KindConnectivity:  "connectivity",
+	KindAuth:          "auth",
+	KindNotFound:      "not-found",
+	KindAlreadyExists: "already-exists",
+	KindKafka:         "kafka",
+}
+
+func (k ErrorKind) String() string {
+	return kindNames[k]
+}
+
+// ExitCode is the process exit code of an error kind.
+func (k ErrorKind) ExitCode() int {
+	return int(k) + DefaultErrorExitCode
+}
+
+// Error is an error with a kind, which decides the exit code, and an optional hint.
+type Error struct {
+	Kind ErrorKind
+	Err  error
+	Hint string
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NewError(kind ErrorKind, err error) *Error {
+	return &Error{Kind: kind, Err: err}
+}
+
+func UsageError(format string, args ...interface{}) error {
+	return &Error{Kind: KindUsage, Err: fmt.Errorf(format, args...)}
+}
+
+func NotFoundError(format string, args ...interface{}) error {
+	return &Error{Kind: KindNotFound, Err: fmt.Errorf(format, args...)}
+}
+
+// Classify returns err as an *Error, deriving its kind and hint from the
+// sarama and network errors it wraps.
+func Classify(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	e = &Error{Kind: KindGeneral, Err: err}
+	if kerr, ok := kafkaError(err); ok {
+		e.Kind = kafkaErrorKind(kerr)
+		e.Hint = KErrorHint(kerr)
+		return e
+	}
+
+	var configErr sarama.ConfigurationError
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Kind = KindConnectivity
+		e.Hint = "the --timeout expired before the brokers answered"
+	case errors.Is(err, context.Canceled):
+		e.Kind = KindGeneral
+	case errors.As(err, &configErr):
+		e.Kind = KindUsage
+	case errors.Is(err, sarama.ErrOutOfBrokers):
+		e.Kind = KindConnectivity
+		e.Hint = "no broker could be reached, run kafka-cli doctor to find out why"
+	case errors.Is(err, sarama.ErrNotConnected), errors.Is(err, sarama.ErrControllerNotAvailable):
+		e.Kind = KindConnectivity
+	case errors.As(err, &dnsErr):
+		e.Kind = KindConnectivity
+		e.Hint = "the broker host name does not resolve"
+	case errors.As(err, &unknownAuthority):
+		e.Kind = KindConnectivity
+		e.Hint = "the broker certificate is not trusted, pass its CA with --tls-ca-file"
+	case errors.As(err, &hostnameErr):
+		e.Kind = KindConnectivity
+		e.Hint = "the broker certificate does not match its host name, see --tls-server-name"
+	case errors.As(err, &netErr), errors.Is(err, io.EOF):
+		e.Kind = KindConnectivity
+	case strings.Contains(err.Error(), "SASL"), strings.Contains(err.Error(), "scram:"):
+		e.Kind = KindAuth
+		e.Hint = "check --sasl-mechanism, --sasl-username and --sasl-password"
+	}
+	return e
+}
+
+// ExitCode returns the process exit code for err, 0 for nil.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return Classify(err).Kind.ExitCode()
+}
+
+func kafkaError(err error) (sarama.KError, bool) {
+	var kerr sarama.KError
+	if errors.As(err, &kerr) {
+		return kerr, kerr != sarama.ErrNoError
+	}
+	var topicErr *sarama.TopicError
+	if errors.As(err, &topicErr) {
+		return topicErr.Err, true
+	}
+	var partitionErr *sarama.TopicPartitionError
+	if errors.As(err, &partitionErr) {
+		return partitionErr.Err, true
+	}
+	return sarama.ErrNoError, false
+}
+
+func kafkaErrorKind(kerr sarama.KError) ErrorKind {
+	switch kerr {
+	case sarama.ErrUnknownTopicOrPartition, sarama.ErrGroupIDNotFound, sarama.ErrDelegationTokenNotFound:
+		return KindNotFound
+	case sarama.ErrTopicAlreadyExists:
+		return KindAlreadyExists
+	case sarama.ErrSASLAuthenticationFailed, sarama.ErrUnsupportedSASLMechanism, sarama.ErrIllegalSASLState,
+		sarama.ErrTopicAuthorizationFailed, sarama.ErrGroupAuthorizationFailed, sarama.ErrClusterAuthorizationFailed,
+		sarama.ErrTransactionalIDAuthorizationFailed, sarama.ErrDelegationTokenAuthorizationFailed:
+		return KindAuth
+	case sarama.ErrLeaderNotAvailable, sarama.ErrBrokerNotAvailable, sarama.ErrRequestTimedOut, sarama.ErrNetworkException:
+		return KindConnectivity
+	}
+	return KindKafka
+}
+
+var kerrorHints = map[sarama.KError]string{
+	sarama.ErrOffsetOutOfRange:                   "the offset is outside the retained log, start from oldest or newest instead",
+	sarama.ErrUnknownTopicOrPartition:            "the topic or partition does not exist, list them with kafka-cli topic -l",
+	sarama.ErrLeaderNotAvailable:                 "a leader election is in progress, retry in a moment",
+	sarama.ErrNotLeaderForPartition:              "the partition leader moved, retry so the metadata gets refreshed",
+	sarama.ErrRequestTimedOut:                    "the broker did not answer in time, the cluster may be overloaded",
+	sarama.ErrBrokerNotAvailable:                 "the broker is down or not registered in the cluster",
+	sarama.ErrMessageSizeTooLarge:                "the message exceeds message.max.bytes of the broker or max.message.bytes of the topic",
+	sarama.ErrInvalidTopic:                       "topic names may only contain letters, digits, '.', '_' and '-'",
+	sarama.ErrNotEnoughReplicas:                  "fewer in sync replicas than min.insync.replicas, check the broker health",
+	sarama.ErrNotEnoughReplicasAfterAppend:       "the write went through but fewer in sync replicas than min.insync.replicas acknowledged it",
+	sarama.ErrInvalidRequiredAcks:                "acks should be 0, 1 or all",
+	sarama.ErrUnknownMemberId:                    "the group member was evicted, the consumer will rejoin",
+	sarama.ErrRebalanceInProgress:                "the group is rebalancing, retry when it is stable",
+	sarama.ErrTopicAuthorizationFailed:           "the principal lacks a topic ACL for this operation",
+	sarama.ErrGroupAuthorizationFailed:           "the principal lacks a group ACL for this operation",
+	sarama.ErrClusterAuthorizationFailed:         "the principal lacks a cluster ACL for this operation",
+	sarama.ErrUnsupportedSASLMechanism:           "the listener does not enable this SASL mechanism, check --sasl-mechanism",
+	sarama.ErrIllegalSASLState:                   "the SASL handshake went out of order, check --kafka-version and the listener protocol",
+	sarama.ErrUnsupportedVersion:                 "the broker does not support this request version, lower --kafka-version or use --kafka-version=auto",
+	sarama.ErrTopicAlreadyExists:                 "the topic already exists, describe it with kafka-cli topic --describe",
+	sarama.ErrInvalidPartitions:                  "the partition count is invalid, it can only grow and must be positive",
+	sarama.ErrInvalidReplicationFactor:           "the replication factor is larger than the number of brokers",
+	sarama.ErrInvalidConfig:                      "the topic or broker config is invalid",
+	sarama.ErrNotController:                      "the controller moved, retry so the metadata gets refreshed",
+	sarama.ErrPolicyViolation:                    "the request violates a create topic policy of the cluster",
+	sarama.ErrSecurityDisabled:                   "security features are disabled on the cluster",
+	sarama.ErrKafkaStorageError:                  "the broker has a disk problem, check its log dirs",
+	sarama.ErrSASLAuthenticationFailed:           "wrong SASL username or password, or the mechanism is not set up for this user",
+	sarama.ErrReassignmentInProgress:             "a partition reassignment is in progress, retry when it finished",
+	sarama.ErrTransactionalIDAuthorizationFailed: "the principal lacks a transactional id ACL",
+	sarama.ErrNonEmptyGroup:                      "the group still has active members, stop them first",
+	sarama.ErrGroupIDNotFound:                    "the group does not exist, list them with kafka-cli admin --list-consumer-groups",
+	sarama.ErrTopicDeletionDisabled:              "delete.topic.enable is false on the brokers",
+	sarama.ErrGroupMaxSizeReached:                "the group reached group.max.size",
+	sarama.ErrOutOfOrderSequenceNumber:           "the idempotent producer lost messages in between, restart the producer",
+	sarama.ErrInvalidProducerEpoch:               "another producer with the same transactional id fenced this one",
+}
+
+// KErrorHint returns a human readable hint for a kafka error code.
+func KErrorHint(kerr sarama.KError) string {
+	return kerrorHints[kerr]
+}
@@ -4,107 +4,685 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/Shopify/sarama"
+	"github.com/thimico/kafka-cli/config"
+	"github.com/thimico/kafka-cli/serde"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func PrintTopic(topic string, detail sarama.TopicDetail) {
-	s, _ := json.MarshalIndent(detail, "", "")
-	printSeparator()
-	fmt.Printf("TOPIC:%s\nDETAIL:%s\n", topic, string(s))
+// CheckResult is the outcome of a single doctor check.
+type CheckResult struct {
+	Target   string        `json:"target" yaml:"target"`
+	Check    string        `json:"check" yaml:"check"`
+	OK       bool          `json:"ok" yaml:"ok"`
+	Duration time.Duration `json:"durationNs" yaml:"durationNs"`
+	Detail   string        `json:"detail,omitempty" yaml:"detail,omitempty"`
+	Hint     string        `json:"hint,omitempty" yaml:"hint,omitempty"`
 }
 
-func PrintTopicMeta(meta *sarama.TopicMetadata) {
-	printSeparator()
-	s, _ := json.MarshalIndent(meta, "", " ")
-	fmt.Printf("TOPIC:%s\nDETAIL:%10s\n", meta.Name, string(s))
+type topicView struct {
+	Name              string            `json:"name" yaml:"name"`
+	NumPartitions     int32             `json:"numPartitions" yaml:"numPartitions"`
+	ReplicationFactor int16             `json:"replicationFactor" yaml:"replicationFactor"`
+	ReplicaAssignment map[int32][]int32 `json:"replicaAssignment,omitempty" yaml:"replicaAssignment,omitempty"`
+	ConfigEntries     map[string]string `json:"configEntries,omitempty" yaml:"configEntries,omitempty"`
 }
 
-func PrintConsumerMessage(msg *sarama.ConsumerMessage) {
-	printSeparator()
-	headers:= map[string]string{}
-	for _,h:= range msg.Headers {
-		headers[string(h.Key)] = string(h.Value)
+type topicList []topicView
+
+func (l topicList) Columns(wide bool) []string {
+	if wide {
+		return []string{"NAME", "PARTITIONS", "REPLICATION", "CONFIGS"}
+	}
+	return []string{"NAME", "PARTITIONS", "REPLICATION"}
+}
+
+func (l topicList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, t := range l {
+		row := []string{t.Name, itoa(t.NumPartitions), itoa(int32(t.ReplicationFactor))}
+		if wide {
+			row = append(row, joinMap(t.ConfigEntries))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (l topicList) Items() []interface{} {
+	return items(l)
+}
+
+func PrintTopics(topics map[string]sarama.TopicDetail) {
+	var l topicList
+	for name, detail := range topics {
+		configs := map[string]string{}
+		for k, v := range detail.ConfigEntries {
+			if v != nil {
+				configs[k] = *v
+			}
+		}
+		l = append(l, topicView{
+			Name:              name,
+			NumPartitions:     detail.NumPartitions,
+			ReplicationFactor: detail.ReplicationFactor,
+			ReplicaAssignment: detail.ReplicaAssignment,
+			ConfigEntries:     configs,
+		})
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Name < l[j].Name })
+	defaultPrinter.print(l)
+}
+
+type partitionView struct {
+	ID              int32   `json:"id" yaml:"id"`
+	Leader          int32   `json:"leader" yaml:"leader"`
+	Replicas        []int32 `json:"replicas" yaml:"replicas"`
+	Isr             []int32 `json:"isr" yaml:"isr"`
+	OfflineReplicas []int32 `json:"offlineReplicas,omitempty" yaml:"offlineReplicas,omitempty"`
+	Err             string  `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type topicMetaView struct {
+	Name       string          `json:"name" yaml:"name"`
+	IsInternal bool            `json:"isInternal" yaml:"isInternal"`
+	Partitions []partitionView `json:"partitions" yaml:"partitions"`
+}
+
+type topicMetaList []topicMetaView
+
+func (l topicMetaList) Columns(wide bool) []string {
+	if wide {
+		return []string{"TOPIC", "PARTITION", "LEADER", "REPLICAS", "ISR", "OFFLINE", "INTERNAL", "ERROR"}
+	}
+	return []string{"TOPIC", "PARTITION", "LEADER", "REPLICAS", "ISR"}
+}
+
+func (l topicMetaList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, t := range l {
+		for _, p := range t.Partitions {
+			row := []string{t.Name, itoa(p.ID), itoa(p.Leader), joinInt32(p.Replicas), joinInt32(p.Isr)}
+			if wide {
+				row = append(row, joinInt32(p.OfflineReplicas), strconv.FormatBool(t.IsInternal), p.Err)
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func (l topicMetaList) Items() []interface{} {
+	return items(l)
+}
+
+func PrintTopicMetas(metas []*sarama.TopicMetadata) {
+	var l topicMetaList
+	for _, meta := range metas {
+		v := topicMetaView{Name: meta.Name, IsInternal: meta.IsInternal}
+		for _, p := range meta.Partitions {
+			pv := partitionView{ID: p.ID, Leader: p.Leader, Replicas: p.Replicas, Isr: p.Isr, OfflineReplicas: p.OfflineReplicas}
+			if p.Err != sarama.ErrNoError {
+				pv.Err = p.Err.Error()
+			}
+			v.Partitions = append(v.Partitions, pv)
+		}
+		sort.Slice(v.Partitions, func(i, j int) bool { return v.Partitions[i].ID < v.Partitions[j].ID })
+		l = append(l, v)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Name < l[j].Name })
+	defaultPrinter.print(l)
+}
+
+type headerView struct {
+	Key   string      `json:"key" yaml:"key"`
+	Value interface{} `json:"value" yaml:"value"`
+}
+
+type messageView struct {
+	Topic          string       `json:"topic" yaml:"topic"`
+	Partition      int32        `json:"partition" yaml:"partition"`
+	Offset         int64        `json:"offset" yaml:"offset"`
+	Timestamp      time.Time    `json:"timestamp" yaml:"timestamp"`
+	BlockTimestamp time.Time    `json:"blockTimestamp" yaml:"blockTimestamp"`
+	Headers        []headerView `json:"headers" yaml:"headers"`
+	Key            interface{}  `json:"key" yaml:"key"`
+	Value          interface{}  `json:"value" yaml:"value"`
+}
+
+func (m messageView) Columns(wide bool) []string {
+	if wide {
+		return []string{"TOPIC", "PARTITION", "OFFSET", "TIMESTAMP", "BLOCK-TIMESTAMP", "HEADERS", "KEY", "VALUE"}
+	}
+	return []string{"TOPIC", "PARTITION", "OFFSET", "TIMESTAMP", "KEY", "VALUE"}
+}
+
+func (m messageView) Rows(wide bool) [][]string {
+	row := []string{m.Topic, itoa(m.Partition), strconv.FormatInt(m.Offset, 10), formatTime(m.Timestamp)}
+	if wide {
+		var headers []string
+		for _, h := range m.Headers {
+			headers = append(headers, h.Key+"="+formatValue(h.Value))
+		}
+		row = append(row, formatTime(m.BlockTimestamp), strings.Join(headers, ","))
+	}
+	return [][]string{append(row, formatValue(m.Key), formatValue(m.Value))}
+}
+
+func (m messageView) Items() []interface{} {
+	return []interface{}{m}
+}
+
+func PrintConsumerMessage(m *serde.Message) {
+	msg := m.Raw
+	v := messageView{
+		Topic:          msg.Topic,
+		Partition:      msg.Partition,
+		Offset:         msg.Offset,
+		Timestamp:      msg.Timestamp,
+		BlockTimestamp: msg.BlockTimestamp,
+		Headers:        []headerView{},
+		Key:            m.Key,
+		Value:          m.Value,
+	}
+	for _, h := range m.Headers {
+		v.Headers = append(v.Headers, headerView{Key: h.Key, Value: h.Value})
+	}
+	defaultPrinter.printStream(v)
+}
+
+// ProduceResult is the outcome of producing one record of an input file.
+type ProduceResult struct {
+	Line      int    `json:"line" yaml:"line"`
+	Topic     string `json:"topic" yaml:"topic"`
+	Partition int32  `json:"partition" yaml:"partition"`
+	Offset    int64  `json:"offset" yaml:"offset"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (r ProduceResult) Columns(wide bool) []string {
+	return []string{"LINE", "TOPIC", "PARTITION", "OFFSET", "ERROR"}
+}
+
+func (r ProduceResult) Rows(wide bool) [][]string {
+	if r.Error != "" {
+		return [][]string{{strconv.Itoa(r.Line), r.Topic, "", "", r.Error}}
 	}
-	fmt.Printf("Headers       :%s\n", headers)
-	fmt.Printf("Timestamp     :%s\n", msg.Timestamp)
-	fmt.Printf("BlockTimestamp:%s\n", msg.BlockTimestamp)
-	fmt.Printf("Key           :%s\n", msg.Key)
-	fmt.Printf("Value         :%s\n", msg.Value)
-	fmt.Printf("Topic         :%s\n", msg.Topic)
-	fmt.Printf("Partition     :%d\n", msg.Partition)
-	fmt.Printf("Offset        :%d\n", msg.Offset)
+	return [][]string{{strconv.Itoa(r.Line), r.Topic, itoa(r.Partition), strconv.FormatInt(r.Offset, 10), ""}}
+}
+
+func (r ProduceResult) Items() []interface{} {
+	return []interface{}{r}
+}
+
+// PrintProduceResult prints the result of a record as soon as it is known.
+func PrintProduceResult(r ProduceResult) {
+	defaultPrinter.printStream(r)
+}
+
+type groupView struct {
+	Name         string `json:"name" yaml:"name"`
+	ProtocolType string `json:"protocolType" yaml:"protocolType"`
+}
+
+type groupList []groupView
+
+func (l groupList) Columns(wide bool) []string {
+	return []string{"NAME", "PROTOCOL-TYPE"}
+}
+
+func (l groupList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, g := range l {
+		rows = append(rows, []string{g.Name, g.ProtocolType})
+	}
+	return rows
+}
+
+func (l groupList) Items() []interface{} {
+	return items(l)
 }
 
 func PrintConsumerGroups(groups map[string]string) {
-	for k,v := range groups {
-		printSeparator()
-		fmt.Printf("Name        :%s\n", k)
-		fmt.Printf("ProtocolType:%s\n", v)
+	var l groupList
+	for name, protocolType := range groups {
+		l = append(l, groupView{Name: name, ProtocolType: protocolType})
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Name < l[j].Name })
+	defaultPrinter.print(l)
+}
+
+type memberView struct {
+	MemberID   string             `json:"memberId" yaml:"memberId"`
+	ClientID   string             `json:"clientId" yaml:"clientId"`
+	ClientHost string             `json:"clientHost" yaml:"clientHost"`
+	Assignment map[string][]int32 `json:"assignment,omitempty" yaml:"assignment,omitempty"`
+}
+
+type groupDetailView struct {
+	GroupID      string       `json:"groupId" yaml:"groupId"`
+	State        string       `json:"state" yaml:"state"`
+	ProtocolType string       `json:"protocolType" yaml:"protocolType"`
+	Protocol     string       `json:"protocol" yaml:"protocol"`
+	Members      []memberView `json:"members" yaml:"members"`
+}
+
+type groupDetailList []groupDetailView
+
+func (l groupDetailList) Columns(wide bool) []string {
+	if wide {
+		return []string{"GROUP", "STATE", "PROTOCOL-TYPE", "PROTOCOL", "MEMBER", "CLIENT-ID", "HOST", "ASSIGNMENT"}
 	}
+	return []string{"GROUP", "STATE", "PROTOCOL", "MEMBER", "CLIENT-ID", "HOST"}
 }
 
-func PrintGroupDetail(g *sarama.GroupDescription) {
-	printSeparator()
-	fmt.Printf("GroupID       :%s\n", g.GroupId)
-	fmt.Printf("State         :%s\n", g.State)
-	fmt.Printf("ProtocolType  :%s\n", g.ProtocolType)
-	fmt.Printf("Protocol      :%s\n", g.Protocol)
-	fmt.Printf("Members       :%s\n", "")
-	for k,v := range g.Members {
-		fmt.Println("    ------------")
-		fmt.Printf("    SessionID  :%s\n", k)
-		fmt.Printf("    ClientID   :%s\n", v.ClientId)
-		fmt.Printf("    ClientHout :%s\n", v.ClientHost)
-		fmt.Printf("    Metadata   :%s\n", string(v.MemberMetadata))
-		fmt.Printf("    Assignment :%s\n", string(v.MemberAssignment))
+func (l groupDetailList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, g := range l {
+		members := g.Members
+		if len(members) == 0 {
+			members = []memberView{{}}
+		}
+		for _, m := range members {
+			var row []string
+			if wide {
+				var assignment []string
+				for _, topic := range sortedKeys(m.Assignment) {
+					assignment = append(assignment, topic+":"+joinInt32(m.Assignment[topic]))
+				}
+				row = []string{g.GroupID, g.State, g.ProtocolType, g.Protocol, m.MemberID, m.ClientID, m.ClientHost, strings.Join(assignment, " ")}
+			} else {
+				row = []string{g.GroupID, g.State, g.Protocol, m.MemberID, m.ClientID, m.ClientHost}
+			}
+			rows = append(rows, row)
+		}
 	}
+	return rows
 }
 
-func PrintConsumerGroupOffsets(group string, res *sarama.OffsetFetchResponse) {
-	printSeparator()
-	fmt.Printf("GroupID        :%s\n", group)
-	fmt.Printf("Version        :%d\n", res.Version)
-	fmt.Printf("ThrottleTimeMs :%d\n", res.ThrottleTimeMs)
-	if res.Err != sarama.ErrNoError {
-		fmt.Printf("Err            :%s\n", res.Err)
+func (l groupDetailList) Items() []interface{} {
+	return items(l)
+}
+
+func PrintGroupDetails(groups []*sarama.GroupDescription) {
+	var l groupDetailList
+	for _, g := range groups {
+		v := groupDetailView{GroupID: g.GroupId, State: g.State, ProtocolType: g.ProtocolType, Protocol: g.Protocol, Members: []memberView{}}
+		for id, m := range g.Members {
+			mv := memberView{MemberID: id, ClientID: m.ClientId, ClientHost: m.ClientHost}
+			if assignment, err := m.GetMemberAssignment(); err == nil && assignment != nil {
+				mv.Assignment = assignment.Topics
+			}
+			v.Members = append(v.Members, mv)
+		}
+		sort.Slice(v.Members, func(i, j int) bool { return v.Members[i].MemberID < v.Members[j].MemberID })
+		l = append(l, v)
 	}
-	for k, v := range res.Blocks {
-		for k1,v1 := range v {
-			fmt.Println("    ------------")
-			fmt.Printf("    Topic       :%s\n", k)
-			fmt.Printf("    Partition   :%d\n", k1)
-			fmt.Printf("    LeaderEpoch :%d\n", v1.LeaderEpoch)
-			fmt.Printf("    Metadata    :%s\n", v1.Metadata)
-			fmt.Printf("    Offset      :%d\n", v1.Offset)
+	sort.Slice(l, func(i, j int) bool { return l[i].GroupID < l[j].GroupID })
+	defaultPrinter.print(l)
+}
+
+type groupOffsetView struct {
+	Group       string `json:"group" yaml:"group"`
+	Topic       string `json:"topic" yaml:"topic"`
+	Partition   int32  `json:"partition" yaml:"partition"`
+	Offset      int64  `json:"offset" yaml:"offset"`
+	LeaderEpoch int32  `json:"leaderEpoch" yaml:"leaderEpoch"`
+	Metadata    string `json:"metadata" yaml:"metadata"`
+	Err         string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type groupOffsetList []groupOffsetView
+
+func (l groupOffsetList) Columns(wide bool) []string {
+	if wide {
+		return []string{"GROUP", "TOPIC", "PARTITION", "OFFSET", "LEADER-EPOCH", "METADATA", "ERROR"}
+	}
+	return []string{"GROUP", "TOPIC", "PARTITION", "OFFSET"}
+}
+
+func (l groupOffsetList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, o := range l {
+		row := []string{o.Group, o.Topic, itoa(o.Partition), strconv.FormatInt(o.Offset, 10)}
+		if wide {
+			row = append(row, itoa(o.LeaderEpoch), o.Metadata, o.Err)
 		}
+		rows = append(rows, row)
 	}
+	return rows
+}
+
+func (l groupOffsetList) Items() []interface{} {
+	return items(l)
+}
+
+// PrintConsumerGroupOffsets prints the offsets fetched for each group.
+func PrintConsumerGroupOffsets(offsets map[string]*sarama.OffsetFetchResponse) {
+	var l groupOffsetList
+	for group, res := range offsets {
+		for topic, partitions := range res.Blocks {
+			for partition, block := range partitions {
+				v := groupOffsetView{Group: group, Topic: topic, Partition: partition, Offset: block.Offset,
+					LeaderEpoch: block.LeaderEpoch, Metadata: block.Metadata}
+				if block.Err != sarama.ErrNoError {
+					v.Err = block.Err.Error()
+				}
+				l = append(l, v)
+			}
+		}
+	}
+	sort.Slice(l, func(i, j int) bool {
+		if l[i].Group != l[j].Group {
+			return l[i].Group < l[j].Group
+		}
+		if l[i].Topic != l[j].Topic {
+			return l[i].Topic < l[j].Topic
+		}
+		return l[i].Partition < l[j].Partition
+	})
+	defaultPrinter.print(l)
+}
+
+type brokerView struct {
+	ID         int32  `json:"id" yaml:"id"`
+	Addr       string `json:"addr" yaml:"addr"`
+	Rack       string `json:"rack,omitempty" yaml:"rack,omitempty"`
+	Controller bool   `json:"controller" yaml:"controller"`
+}
+
+type brokerList []brokerView
+
+func (l brokerList) Columns(wide bool) []string {
+	if wide {
+		return []string{"BROKER-ID", "ADDRESS", "CONTROLLER", "RACK"}
+	}
+	return []string{"BROKER-ID", "ADDRESS", "CONTROLLER"}
+}
+
+func (l brokerList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, b := range l {
+		row := []string{itoa(b.ID), b.Addr, strconv.FormatBool(b.Controller)}
+		if wide {
+			row = append(row, b.Rack)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (l brokerList) Items() []interface{} {
+	return items(l)
 }
 
 func PrintCluster(controllerID int32, brokers []*sarama.Broker) {
-	printSeparator()
-	fmt.Println("ControllerID:", controllerID)
+	var l brokerList
 	for _, b := range brokers {
-		fmt.Printf("%-10s%-30s\n", "BrokerID", "BrokerAddr")
-		fmt.Printf("%-10d%-30s\n",b.ID(), b.Addr())
+		l = append(l, brokerView{ID: b.ID(), Addr: b.Addr(), Rack: b.Rack(), Controller: b.ID() == controllerID})
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].ID < l[j].ID })
+	defaultPrinter.print(l)
+}
+
+type logDirView struct {
+	Broker      int32  `json:"broker" yaml:"broker"`
+	Path        string `json:"path" yaml:"path"`
+	Topic       string `json:"topic" yaml:"topic"`
+	Partition   int32  `json:"partition" yaml:"partition"`
+	Size        int64  `json:"size" yaml:"size"`
+	OffsetLag   int64  `json:"offsetLag" yaml:"offsetLag"`
+	IsTemporary bool   `json:"isTemporary" yaml:"isTemporary"`
+	Err         string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type logDirList []logDirView
+
+func (l logDirList) Columns(wide bool) []string {
+	if wide {
+		return []string{"BROKER", "PATH", "TOPIC", "PARTITION", "SIZE", "OFFSET-LAG", "TEMPORARY", "ERROR"}
+	}
+	return []string{"BROKER", "PATH", "TOPIC", "PARTITION", "SIZE", "OFFSET-LAG"}
+}
+
+func (l logDirList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, d := range l {
+		row := []string{itoa(d.Broker), d.Path, d.Topic, itoa(d.Partition), strconv.FormatInt(d.Size, 10), strconv.FormatInt(d.OffsetLag, 10)}
+		if wide {
+			row = append(row, strconv.FormatBool(d.IsTemporary), d.Err)
+		}
+		rows = append(rows, row)
 	}
+	return rows
+}
 
+func (l logDirList) Items() []interface{} {
+	return items(l)
 }
 
 func PrintLogDirs(info map[int32][]sarama.DescribeLogDirsResponseDirMetadata) {
-	for k, v := range info {
-		printSeparator()
-		fmt.Printf("BrokerID:%d\n", k)
-		for _, d := range v {
-			fmt.Printf("Path: %s\n", d.Path)
-			fmt.Printf("%-50s%-15s%-20s%-20s%-20s\n", "Topic", "IsTemporary", "OffsetLag", "PartitionID", "Size")
+	var l logDirList
+	for broker, dirs := range info {
+		for _, d := range dirs {
+			errMsg := ""
+			if d.ErrorCode != sarama.ErrNoError {
+				errMsg = d.ErrorCode.Error()
+			}
+			if len(d.Topics) == 0 {
+				l = append(l, logDirView{Broker: broker, Path: d.Path, Partition: -1, Err: errMsg})
+			}
 			for _, t := range d.Topics {
 				for _, p := range t.Partitions {
-					fmt.Printf("%-50s%-15t%-20d%-20d%-20d\n",t.Topic, p.IsTemporary, p.OffsetLag, p.PartitionID, p.Size)
+					l = append(l, logDirView{Broker: broker, Path: d.Path, Topic: t.Topic, Partition: p.PartitionID,
+						Size: p.Size, OffsetLag: p.OffsetLag, IsTemporary: p.IsTemporary, Err: errMsg})
 				}
 			}
 		}
 	}
+	sort.Slice(l, func(i, j int) bool {
+		if l[i].Broker != l[j].Broker {
+			return l[i].Broker < l[j].Broker
+		}
+		if l[i].Path != l[j].Path {
+			return l[i].Path < l[j].Path
+		}
+		if l[i].Topic != l[j].Topic {
+			return l[i].Topic < l[j].Topic
+		}
+		return l[i].Partition < l[j].Partition
+	})
+	defaultPrinter.print(l)
+}
+
+type contextView struct {
+	Name    string   `json:"name" yaml:"name"`
+	Current bool     `json:"current" yaml:"current"`
+	Version string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Brokers []string `json:"brokers" yaml:"brokers"`
+}
+
+type contextList []contextView
+
+func (l contextList) Columns(wide bool) []string {
+	return []string{"CURRENT", "NAME", "VERSION", "BROKERS"}
+}
+
+func (l contextList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, c := range l {
+		current := ""
+		if c.Current {
+			current = "*"
+		}
+		rows = append(rows, []string{current, c.Name, c.Version, strings.Join(c.Brokers, ",")})
+	}
+	return rows
+}
+
+func (l contextList) Items() []interface{} {
+	return items(l)
+}
+
+func PrintContexts(cfg *config.Config) {
+	var l contextList
+	for _, name := range cfg.ContextNames() {
+		ctx := cfg.Contexts[name]
+		l = append(l, contextView{Name: name, Current: name == cfg.CurrentContext, Version: ctx.Version, Brokers: ctx.Brokers})
+	}
+	defaultPrinter.print(l)
+}
+
+type currentContextView struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+func (c currentContextView) Columns(wide bool) []string {
+	return []string{"NAME"}
 }
 
-func printSeparator() {
-	fmt.Println("*****************************************************")
+func (c currentContextView) Rows(wide bool) [][]string {
+	return [][]string{{c.Name}}
+}
+
+func (c currentContextView) Items() []interface{} {
+	return []interface{}{c}
+}
+
+func PrintCurrentContext(name string) {
+	defaultPrinter.print(currentContextView{Name: name})
+}
+
+type checkResultList []CheckResult
+
+func (l checkResultList) Columns(wide bool) []string {
+	return []string{"TARGET", "CHECK", "RESULT", "TIME", "DETAIL", "HINT"}
+}
+
+func (l checkResultList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, r := range l {
+		result := "PASS"
+		if !r.OK {
+			result = "FAIL"
+		}
+		rows = append(rows, []string{r.Target, r.Check, result, r.Duration.Round(time.Millisecond).String(), r.Detail, r.Hint})
+	}
+	return rows
+}
+
+func (l checkResultList) Items() []interface{} {
+	return items(l)
+}
+
+func PrintCheckResults(results []CheckResult) {
+	defaultPrinter.print(checkResultList(results))
+}
+
+// PerfReport is the throughput of a perf test window, or of the whole run,
+// Latency is nil when the test measures none.
+type PerfReport struct {
+	Window        string         `json:"window" yaml:"window"`
+	Elapsed       time.Duration  `json:"elapsedNs" yaml:"elapsedNs"`
+	Records       int64          `json:"records" yaml:"records"`
+	RecordsPerSec float64        `json:"recordsPerSec" yaml:"recordsPerSec"`
+	MBPerSec      float64        `json:"mbPerSec" yaml:"mbPerSec"`
+	Errors        int64          `json:"errors" yaml:"errors"`
+	Latency       *LatencyReport `json:"latency,omitempty" yaml:"latency,omitempty"`
+}
+
+// LatencyReport holds latency percentiles in milliseconds.
+type LatencyReport struct {
+	P50  float64 `json:"p50Ms" yaml:"p50Ms"`
+	P95  float64 `json:"p95Ms" yaml:"p95Ms"`
+	P99  float64 `json:"p99Ms" yaml:"p99Ms"`
+	Max  float64 `json:"maxMs" yaml:"maxMs"`
+	Mean float64 `json:"meanMs" yaml:"meanMs"`
+}
+
+func (r PerfReport) Columns(wide bool) []string {
+	columns := []string{"WINDOW", "ELAPSED", "RECORDS", "RECORDS/SEC", "MB/SEC", "ERRORS"}
+	if r.Latency != nil {
+		columns = append(columns, "P50(MS)", "P95(MS)", "P99(MS)", "MAX(MS)")
+		if wide {
+			columns = append(columns, "MEAN(MS)")
+		}
+	}
+	return columns
+}
+
+func (r PerfReport) Rows(wide bool) [][]string {
+	row := []string{r.Window, r.Elapsed.Round(time.Millisecond).String(), strconv.FormatInt(r.Records, 10),
+		fmt.Sprintf("%.1f", r.RecordsPerSec), fmt.Sprintf("%.2f", r.MBPerSec), strconv.FormatInt(r.Errors, 10)}
+	if l := r.Latency; l != nil {
+		row = append(row, fmt.Sprintf("%.1f", l.P50), fmt.Sprintf("%.1f", l.P95), fmt.Sprintf("%.1f", l.P99), fmt.Sprintf("%.1f", l.Max))
+		if wide {
+			row = append(row, fmt.Sprintf("%.1f", l.Mean))
+		}
+	}
+	return [][]string{row}
+}
+
+func (r PerfReport) Items() []interface{} {
+	return []interface{}{r}
+}
+
+// PrintPerfReport prints a report as soon as its window ends.
+func PrintPerfReport(r PerfReport) {
+	defaultPrinter.printStream(r)
+}
+
+func itoa(i int32) string {
+	return strconv.FormatInt(int64(i), 10)
+}
+
+func joinInt32(ids []int32) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = itoa(id)
+	}
+	return strings.Join(s, ",")
+}
+
+func joinMap(m map[string]string) string {
+	var kv []string
+	for k, v := range m {
+		kv = append(kv, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(kv)
+	return strings.Join(kv, ",")
+}
+
+func sortedKeys(m map[string][]int32) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// formatValue renders a decoded key or value in a table cell, documents of
+// schema based formats as compact json.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
 }
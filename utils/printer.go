@@ -0,0 +1,270 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"text/template"
+	"unicode/utf8"
+)
+
+const (
+	OutputTable    = "table"
+	OutputWide     = "wide"
+	OutputJSON     = "json"
+	OutputYAML     = "yaml"
+	OutputNDJSON   = "ndjson"
+	OutputTemplate = "template"
+)
+
+// Printable is implemented by every object the Print* functions output.
+type Printable interface {
+	// Columns returns the table header, wide adds the columns of -o wide.
+	Columns(wide bool) []string
+	// Rows returns the table rows, in the order of Columns.
+	Rows(wide bool) [][]string
+	// Items returns the elements of a list, used by ndjson and template output.
+	// Single objects return themselves.
+	Items() []interface{}
+}
+
+// items returns the elements of a slice for Printable.Items.
+func items(slice interface{}) []interface{} {
+	v := reflect.ValueOf(slice)
+	out := make([]interface{}, v.Len())
+	for i := range out {
+		out[i] = v.Index(i).Interface()
+	}
+	return out
+}
+
+// Renderer writes a Printable in one output format.
+type Renderer interface {
+	Render(w io.Writer, obj Printable) error
+}
+
+// streamRenderer is implemented by renderers which need to know about objects
+// printed one after another, such as consumed messages.
+type streamRenderer interface {
+	RenderStream(w io.Writer, obj Printable, s *stream) error
+}
+
+// stream is what is kept between the objects of a stream.
+type stream struct {
+	// started is set once the first object is printed
+	started bool
+	// widths are the column widths of a table, see tableRenderer.RenderStream
+	widths []int
+}
+
+type tableRenderer struct {
+	wide bool
+}
+
+// tablePadding is the space between the columns of a table.
+const tablePadding = 2
+
+func (r tableRenderer) Render(w io.Writer, obj Printable) error {
+	tw := tabwriter.NewWriter(w, 0, 4, tablePadding, ' ', 0)
+	writeRow(tw, obj.Columns(r.wide))
+	for _, row := range obj.Rows(r.wide) {
+		writeRow(tw, row)
+	}
+	return tw.Flush()
+}
+
+// RenderStream prints the header only once. Later rows are unknown, so the
+// columns are sized like Render to the header and the rows printed so far, a
+// column widens when a row does not fit.
+func (r tableRenderer) RenderStream(w io.Writer, obj Printable, s *stream) error {
+	columns := obj.Columns(r.wide)
+	rows := obj.Rows(r.wide)
+	if !s.started {
+		s.widths = growWidths(nil, columns)
+	}
+	for _, row := range rows {
+		s.widths = growWidths(s.widths, row)
+	}
+	if !s.started {
+		fmt.Fprintln(w, padRow(columns, s.widths))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(w, padRow(row, s.widths))
+	}
+	return nil
+}
+
+func writeRow(w io.Writer, row []string) {
+	fmt.Fprintln(w, strings.Join(row, "\t"))
+}
+
+// growWidths widens widths to the cells of row.
+func growWidths(widths []int, row []string) []int {
+	for i, cell := range row {
+		n := utf8.RuneCountInString(cell)
+		if i == len(widths) {
+			widths = append(widths, n)
+		} else if n > widths[i] {
+			widths[i] = n
+		}
+	}
+	return widths
+}
+
+// padRow pads every cell but the last to its column width and the padding of
+// Render.
+func padRow(row []string, widths []int) string {
+	cells := make([]string, len(row))
+	for i, cell := range row {
+		if i == len(row)-1 {
+			cells[i] = cell
+			continue
+		}
+		cells[i] = fmt.Sprintf("%-*s", widths[i]+tablePadding, cell)
+	}
+	return strings.Join(cells, "")
+}
+
+type jsonRenderer struct{}
+
+func (jsonRenderer) Render(w io.Writer, obj Printable) error {
+	// an empty list is a nil slice, which would be null
+	if len(obj.Items()) == 0 {
+		_, err := fmt.Fprintln(w, "[]")
+		return err
+	}
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+type yamlRenderer struct{}
+
+func (yamlRenderer) Render(w io.Writer, obj Printable) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (r yamlRenderer) RenderStream(w io.Writer, obj Printable, s *stream) error {
+	fmt.Fprintln(w, "---")
+	return r.Render(w, obj)
+}
+
+type ndjsonRenderer struct{}
+
+func (ndjsonRenderer) Render(w io.Writer, obj Printable) error {
+	enc := json.NewEncoder(w)
+	for _, item := range obj.Items() {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type templateRenderer struct {
+	tmpl *template.Template
+}
+
+func (r templateRenderer) Render(w io.Writer, obj Printable) error {
+	for _, item := range obj.Items() {
+		var sb strings.Builder
+		if err := r.tmpl.Execute(&sb, item); err != nil {
+			return err
+		}
+		out := sb.String()
+		if !strings.HasSuffix(out, "\n") {
+			out += "\n"
+		}
+		if _, err := io.WriteString(w, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewRenderer parses an output format, one of table, wide, json, yaml,
+// ndjson or template=<go template>.
+func NewRenderer(format string) (Renderer, error) {
+	switch format {
+	case OutputTable, "":
+		return tableRenderer{}, nil
+	case OutputWide:
+		return tableRenderer{wide: true}, nil
+	case OutputJSON:
+		return jsonRenderer{}, nil
+	case OutputYAML:
+		return yamlRenderer{}, nil
+	case OutputNDJSON:
+		return ndjsonRenderer{}, nil
+	}
+	if strings.HasPrefix(format, OutputTemplate+"=") {
+		tmpl, err := template.New("output").Parse(strings.TrimPrefix(format, OutputTemplate+"="))
+		if err != nil {
+			return nil, UsageError("invalid output template: %s", err)
+		}
+		return templateRenderer{tmpl: tmpl}, nil
+	}
+	return nil, UsageError("unknown output format %q, should be table, wide, json, yaml, ndjson or template=...", format)
+}
+
+// printer serializes the output of all Print* functions.
+type printer struct {
+	mu       sync.Mutex
+	out      io.Writer
+	renderer Renderer
+	stream   stream
+}
+
+var defaultPrinter = &printer{out: os.Stdout, renderer: tableRenderer{}}
+
+// SetOutputFormat selects the renderer of every Print* function.
+func SetOutputFormat(format string) error {
+	r, err := NewRenderer(format)
+	if err != nil {
+		return err
+	}
+	defaultPrinter.mu.Lock()
+	defaultPrinter.renderer = r
+	defaultPrinter.mu.Unlock()
+	return nil
+}
+
+func (p *printer) print(obj Printable) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.renderer.Render(p.out, obj); err != nil {
+		LogError(err)
+	}
+}
+
+func (p *printer) printStream(obj Printable) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var err error
+	if sr, ok := p.renderer.(streamRenderer); ok {
+		err = sr.RenderStream(p.out, obj, &p.stream)
+	} else if _, ok := p.renderer.(jsonRenderer); ok {
+		// a stream of indented documents is not parseable, keep one per line
+		err = ndjsonRenderer{}.Render(p.out, obj)
+	} else {
+		err = p.renderer.Render(p.out, obj)
+	}
+	p.stream.started = true
+	if err != nil {
+		LogError(err)
+	}
+}
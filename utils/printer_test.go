@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRenderEmptyList(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{OutputJSON, "[]\n"},
+		{OutputYAML, "[]\n"},
+		{OutputNDJSON, ""},
+		{OutputTable, "NAME  PARTITIONS  REPLICATION\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			r, err := NewRenderer(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := r.Render(&buf, topicList(nil)); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	r, err := NewRenderer(OutputJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := r.Render(&buf, currentContextView{Name: "prod"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"name\": \"prod\"\n}\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRenderStreamTable(t *testing.T) {
+	var buf bytes.Buffer
+	var s stream
+	for _, r := range []ProduceResult{
+		{Line: 1, Topic: "t", Partition: 0, Offset: 7},
+		{Line: 2, Topic: "orders.created", Error: "Message was too large"},
+		{Line: 10, Topic: "t", Partition: 11, Offset: 12345678901234},
+	} {
+		if err := (tableRenderer{}).RenderStream(&buf, r, &s); err != nil {
+			t.Fatal(err)
+		}
+		s.started = true
+	}
+	// the columns are sized to the rows printed so far, the topic column
+	// widens at line 2
+	want := "LINE  TOPIC  PARTITION  OFFSET  ERROR\n" +
+		"1     t      0          7       \n" +
+		"2     orders.created                     Message was too large\n" +
+		"10    t               11         12345678901234  \n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestItems(t *testing.T) {
+	l := brokerList{{ID: 1}, {ID: 2}}
+	items := l.Items()
+	if len(items) != 2 || items[1].(brokerView).ID != 2 {
+		t.Errorf("items = %v", items)
+	}
+	if items := topicList(nil).Items(); len(items) != 0 {
+		t.Errorf("items of an empty list = %v", items)
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"context"
+	"github.com/thimico/kafka-cli/log"
+	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// SignalContext returns a context which is cancelled on the first SIGINT or
+// SIGTERM, so commands can drain, commit and close before exiting. A second
+// signal kills the process right away.
+func SignalContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-ch:
+			log.Info("Shutting down, signal again to force", zap.String("signal", sig.String()))
+			signal.Stop(ch)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(ch)
+		cancel()
+	}
+}